@@ -57,18 +57,21 @@ func main() {
 	defer db.Close()
 
 	// Initialize cache
-	cache := cache.New(&cfg.Cache)
-	defer cache.StopCleanup()
+	orderCache, err := cache.Open(&cfg.Cache)
+	if err != nil {
+		config.RLogger.Fatalf("Error creating cache: %v", err)
+	}
+	defer orderCache.StopCleanup()
 
-	// Load cache from database
-	cache.ResetDB(db)
+	// Load cache from snapshot or database
+	cache.Restore(orderCache, db, &cfg.Cache)
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize and start Kafka consumer
-	consumer, err := consumer.New(cfg, cache, db)
+	consumer, err := consumer.New(cfg, orderCache, db)
 	if err != nil {
 		config.RLogger.Fatalf("Error creating Kafka consumer: %v", err)
 	}
@@ -80,10 +83,27 @@ func main() {
 		}
 	}()
 
+	// Periodically persist cache snapshot
+	go cache.RunSnapshots(ctx, orderCache, &cfg.Cache)
+
 	// Setup HTTP handlers
-	h := handler.New(cache, db)
+	h := handler.New(orderCache, db)
 	wrappedHandler := enableCORS(loggingMiddleware(h.OrderHandler))
 	http.HandleFunc("/order/", wrappedHandler)
+	http.HandleFunc("/orders", enableCORS(loggingMiddleware(h.ListOrdersHandler)))
+	http.HandleFunc("/orders:batchGet", enableCORS(loggingMiddleware(h.BatchGetHandler)))
+	http.HandleFunc("/orders/by-track/{track_number}", enableCORS(loggingMiddleware(h.OrderByTrackHandler)))
+	http.HandleFunc("/orders/by-transaction/{transaction}", enableCORS(loggingMiddleware(h.OrderByTransactionHandler)))
+	http.HandleFunc("/orders/by-rid/{rid}", enableCORS(loggingMiddleware(h.OrderByRidHandler)))
+	http.HandleFunc("/customers/{customer_id}/orders", enableCORS(loggingMiddleware(h.CustomerOrdersHandler)))
+	http.HandleFunc("/search", enableCORS(loggingMiddleware(h.SearchHandler)))
+	http.HandleFunc("/cache/stats", enableCORS(loggingMiddleware(h.CacheStatsHandler)))
+
+	if cfg.Admin.Token != "" {
+		handler.NewAdmin(orderCache, db, cfg.Admin.Token).Register(http.DefaultServeMux)
+	} else {
+		config.RLogger.Println("Admin token is not set, admin endpoints are disabled")
+	}
 
 	// Configure HTTP server
 	server := &http.Server{
@@ -116,13 +136,19 @@ func main() {
 	// Graceful shutdown
 	cancel() // Stop Kafka consumer
 
+	// Persist cache snapshot for the next start
+	cache.WriteSnapshot(orderCache, &cfg.Cache)
+
 	// Print cache stats before shutdown
-	if count, maxSize, keys := cache.GetStats(); count > 0 {
-		config.RLogger.Printf("Cache stats: %d/%d elements", count, maxSize)
-		if len(keys) > 5 {
-			config.RLogger.Printf("First 5 cache keys: %v", keys[:5])
+	if stats := orderCache.GetStats(); stats.Count > 0 {
+		config.RLogger.Printf("Cache stats: %d/%d elements, %d/%d bytes",
+			stats.Count, stats.MaxSize, stats.UsedBytes, stats.MaxBytes)
+		config.RLogger.Printf("Cache hits: %d, misses: %d (hit ratio %.2f), evictions: %d by capacity, %d by TTL",
+			stats.Hits, stats.Misses, stats.HitRatio, stats.CapacityEvictions, stats.ExpiredEvictions)
+		if len(stats.Keys) > 5 {
+			config.RLogger.Printf("First 5 cache keys: %v", stats.Keys[:5])
 		} else {
-			config.RLogger.Printf("Cache keys: %v", keys)
+			config.RLogger.Printf("Cache keys: %v", stats.Keys)
 		}
 	}
 
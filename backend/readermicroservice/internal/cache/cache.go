@@ -1,88 +1,198 @@
 package cache
 
 import (
-	"sync"
+	"context"
+	"math"
+	"sort"
 	"time"
 
 	"readermicroservice/internal/config"
 	"readermicroservice/internal/models"
 )
 
-type cacheItem struct {
-	order      models.Order
-	lastAccess time.Time
-	createdAt  time.Time
-}
+// defaultShards — количество шардов, если оно не задано в конфигурации
+const defaultShards = 16
 
-// Cache реализует интерфейс OrderCache
+// Cache реализует интерфейс OrderCache. Заказы распределяются по независимо
+// блокируемым шардам по хешу order_uid, поэтому чтения не блокируют друг друга,
+// а запись из консюмера блокирует только один шард
 type Cache struct {
-	mu              sync.RWMutex
-	elements        map[string]*cacheItem
-	maxSize         int
-	defaultTTL      time.Duration
-	cleanupInterval time.Duration
-	stopCleanup     chan bool
+	shards            []*shard
+	maxSize           int
+	maxBytes          int64
+	ttl               ttlPolicy
+	slidingExpiration bool
+	missing           *negativeCache // nil, если негативное кэширование выключено
+	cleanupInterval   time.Duration
+	stopCleanup       chan bool
 }
 
 // New создает новый экземпляр кэша
 func New(conf *config.CacheConfig) *Cache {
+	n := conf.Shards
+	if n <= 0 {
+		n = defaultShards
+	}
+	// У каждого шарда должно быть место хотя бы под один заказ
+	if conf.MaxSize > 0 && n > conf.MaxSize {
+		n = conf.MaxSize
+	}
+
 	c := &Cache{
-		elements:        make(map[string]*cacheItem),
-		maxSize:         conf.MaxSize,
-		defaultTTL:      conf.DefaultTTL,
-		cleanupInterval: conf.CleanupInterval,
-		stopCleanup:     make(chan bool),
+		shards:            make([]*shard, n),
+		maxSize:           conf.MaxSize,
+		maxBytes:          conf.MaxBytes,
+		ttl:               newTTLPolicy(conf),
+		slidingExpiration: conf.SlidingExpiration,
+		cleanupInterval:   conf.CleanupInterval,
+		stopCleanup:       make(chan bool),
+	}
+	if conf.NegativeTTL > 0 {
+		c.missing = newNegativeCache(conf.NegativeTTL, conf.NegativeMaxSize)
+	}
+
+	// Емкость и бюджет памяти делятся между шардами так, чтобы в сумме
+	// получились maxSize и maxBytes
+	for i := range c.shards {
+		size := conf.MaxSize / n
+		if i < conf.MaxSize%n {
+			size++
+		}
+		bytes := conf.MaxBytes / int64(n)
+		if int64(i) < conf.MaxBytes%int64(n) {
+			bytes++
+		}
+		c.shards[i] = newShard(size, bytes, conf.Eviction)
 	}
 
 	go c.startCleanup()
 	return c
 }
 
-// Add добавляет элемент в кэш
+// shardFor выбирает шард по FNV-1a хешу order_uid
+func (c *Cache) shardFor(orderUID string) *shard {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(orderUID); i++ {
+		h ^= uint32(orderUID[i])
+		h *= prime32
+	}
+	return c.shards[h%uint32(len(c.shards))]
+}
+
+// Add добавляет элемент в кэш или заменяет уже закэшированную версию заказа.
+// Срок жизни выбирается по статусу заказа (см. ttlPolicy)
 func (c *Cache) Add(order models.Order) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.AddWithTTL(order, c.ttl.ttlFor(order))
+}
+
+// AddWithTTL добавляет элемент с собственным сроком жизни; ttl <= 0 — без истечения
+func (c *Cache) AddWithTTL(order models.Order, ttl time.Duration) {
+	c.forgetMissing(order.OrderUID)
+	c.shardFor(order.OrderUID).add(order, ttl, time.Now(), true)
+}
+
+// AddIfAbsent добавляет заказ, только если его еще нет в кэше, и сообщает,
+// был ли заказ добавлен. Используется для заполнения кэша при чтении из БД,
+// чтобы не перезаписать более свежую версию, добавленную консюмером
+func (c *Cache) AddIfAbsent(order models.Order) bool {
+	c.forgetMissing(order.OrderUID)
+	return c.shardFor(order.OrderUID).add(order, c.ttl.ttlFor(order), time.Now(), false)
+}
+
+// MarkMissing запоминает на negative_ttl, что заказа нет в БД. Если заказ уже
+// успел попасть в кэш (например, его только что сохранил консюмер), отметка не ставится
+func (c *Cache) MarkMissing(orderUID string) {
+	if c.missing == nil {
+		return
+	}
+	now := time.Now()
+	if c.shardFor(orderUID).contains(orderUID, now) {
+		return
+	}
+	c.missing.add(orderUID, now)
+}
+
+// IsMissing сообщает, известно ли, что заказа нет в БД
+func (c *Cache) IsMissing(orderUID string) bool {
+	return c.missing != nil && c.missing.contains(orderUID, time.Now())
+}
 
-	if len(c.elements) >= c.maxSize {
-		c.evictOldest()
+// GetMany проверяет заказы по одному: обращение к памяти процесса дешево
+func (c *Cache) GetMany(orderUIDs []string) (map[string]models.Order, map[string]bool) {
+	orders := make(map[string]models.Order, len(orderUIDs))
+	missing := make(map[string]bool)
+	for _, uid := range orderUIDs {
+		if order, ok := c.Get(uid); ok {
+			orders[uid] = order
+		} else if c.IsMissing(uid) {
+			missing[uid] = true
+		}
 	}
+	return orders, missing
+}
 
-	c.elements[order.OrderUID] = &cacheItem{
-		order:      order,
-		lastAccess: time.Now(),
-		createdAt:  time.Now(),
+// AddManyIfAbsent добавляет каждый заказ через AddIfAbsent
+func (c *Cache) AddManyIfAbsent(orders []models.Order) {
+	for _, order := range orders {
+		c.AddIfAbsent(order)
 	}
 }
 
-// Get получает элемент из кэша
-func (c *Cache) Get(orderUID string) (models.Order, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// MarkManyMissing ставит отметку об отсутствии каждому заказу через MarkMissing
+func (c *Cache) MarkManyMissing(orderUIDs []string) {
+	for _, uid := range orderUIDs {
+		c.MarkMissing(uid)
+	}
+}
 
-	item, exists := c.elements[orderUID]
-	if !exists {
-		return models.Order{}, false
+// forgetMissing снимает отметку об отсутствии заказа при его добавлении в кэш
+func (c *Cache) forgetMissing(orderUID string) {
+	if c.missing != nil {
+		c.missing.remove(orderUID)
 	}
+}
 
-	item.lastAccess = time.Now()
-	return item.order, true
+// Get получает элемент из кэша. Истекшие элементы не возвращаются,
+// даже если периодическая очистка до них еще не дошла
+func (c *Cache) Get(orderUID string) (models.Order, bool) {
+	s := c.shardFor(orderUID)
+	now := time.Now()
+	order, ok := s.get(orderUID, now, c.slidingExpiration)
+	s.getTime.Add(int64(time.Since(now)))
+	return order, ok
 }
 
-// evictOldest удаляет самый старый по доступу элемент
-func (c *Cache) evictOldest() {
-	var oldestKey string
-	var oldestTime time.Time
+// Delete удаляет заказ и отметку о его отсутствии
+func (c *Cache) Delete(orderUID string) bool {
+	c.forgetMissing(orderUID)
+	return c.shardFor(orderUID).delete(orderUID)
+}
 
-	for key, item := range c.elements {
-		if oldestKey == "" || item.lastAccess.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = item.lastAccess
-		}
+// Flush удаляет все заказы и отметки об отсутствии. Счетчики статистики сохраняются
+func (c *Cache) Flush() {
+	for _, s := range c.shards {
+		s.reset()
+	}
+	if c.missing != nil {
+		c.missing.reset()
 	}
+}
 
-	if oldestKey != "" {
-		delete(c.elements, oldestKey)
+// Entries возвращает сведения о действующих элементах кэша, от старых к новым
+func (c *Cache) Entries() []EntryInfo {
+	now := time.Now()
+	var entries []EntryInfo
+	for _, s := range c.shards {
+		entries = s.appendEntries(entries, now)
 	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries
 }
 
 // startCleanup запускает периодическую очистку просроченных элементов
@@ -100,16 +210,11 @@ func (c *Cache) startCleanup() {
 	}
 }
 
-// cleanupExpired удаляет просроченные элементы
+// cleanupExpired удаляет просроченные элементы, блокируя шарды по очереди
 func (c *Cache) cleanupExpired() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	now := time.Now()
-	for key, item := range c.elements {
-		if now.Sub(item.createdAt) > c.defaultTTL {
-			delete(c.elements, key)
-		}
+	for _, s := range c.shards {
+		s.removeExpired(now)
 	}
 }
 
@@ -118,38 +223,51 @@ func (c *Cache) StopCleanup() {
 	close(c.stopCleanup)
 }
 
-// GetStats возвращает статистику кэша
-func (c *Cache) GetStats() (int, int, []string) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	keys := make([]string, 0, len(c.elements))
-	for k := range c.elements {
-		keys = append(keys, k)
+// GetStats возвращает статистику кэша: оценку занимаемой памяти и счетчики
+// попаданий, промахов, вставок и вытеснений
+func (c *Cache) GetStats() Stats {
+	st := Stats{
+		MaxSize:  c.maxSize,
+		MaxBytes: c.maxBytes,
+		Keys:     make([]string, 0, c.maxSize),
+	}
+	for _, s := range c.shards {
+		s.collectStats(&st)
 	}
-	return len(c.elements), c.maxSize, keys
+	st.derive()
+	return st
 }
 
-// ResetDB загружает данные из БД в кэш
+// ResetDB очищает кэш и заполняет его самыми новыми заказами из БД, пока они помещаются
+// в maxSize и maxBytes. Заказы читаются потоково, блокировка шарда берется только
+// на время вставки одного заказа и не удерживается во время запросов к БД
 func (c *Cache) ResetDB(db OrderDatabase) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.Flush()
+
+	limit := c.maxSize
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
 
-	orders, err := db.GetAll()
+	now := time.Now()
+	loaded, rejected := 0, 0
+	err := db.IterateRecent(context.Background(), limit, func(order models.Order) bool {
+		// Заказы идут от новых к старым, поэтому более старые встают ближе
+		// к концу списка шарда и вытесняются первыми. Заказ, добавленный
+		// консюмером во время прогрева, не перезаписывается
+		if c.shardFor(order.OrderUID).fill(order, c.ttl.ttlFor(order), now) {
+			loaded++
+			rejected = 0
+		} else {
+			rejected++
+		}
+		// Если подряд не поместилось столько заказов, сколько шардов, кэш считается заполненным
+		return loaded < limit && rejected < len(c.shards)
+	})
 	if err != nil {
 		config.RLogger.Printf("Error loading data from DB to cache: %v", err)
 		return
 	}
 
-	c.elements = make(map[string]*cacheItem)
-
-	for _, item := range orders {
-		if len(c.elements) < c.maxSize {
-			c.elements[item.OrderUID] = &cacheItem{
-				order:      item,
-				lastAccess: time.Now(),
-				createdAt:  time.Now(),
-			}
-		}
-	}
+	config.RLogger.Printf("Cache warmed up with %d orders", loaded)
 }
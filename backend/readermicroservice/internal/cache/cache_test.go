@@ -0,0 +1,433 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+func TestMain(m *testing.M) {
+	config.RLogger = log.New(os.Stdout, "TEST: ", log.LstdFlags)
+	os.Exit(m.Run())
+}
+
+func newTestCache(tb testing.TB, maxSize, shards int) *Cache {
+	tb.Helper()
+
+	c := New(&config.CacheConfig{
+		MaxSize:         maxSize,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		Shards:          shards,
+	})
+	tb.Cleanup(c.StopCleanup)
+	return c
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTestCache(t, 3, 1)
+
+	c.Add(models.Order{OrderUID: "a"})
+	c.Add(models.Order{OrderUID: "b"})
+	c.Add(models.Order{OrderUID: "c"})
+
+	// "a" становится последним использованным, вытеснен должен быть "b"
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	c.Add(models.Order{OrderUID: "d"})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, uid := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(uid); !ok {
+			t.Errorf("Expected %s to be cached", uid)
+		}
+	}
+}
+
+func TestCache_UpdateDoesNotEvict(t *testing.T) {
+	c := newTestCache(t, 2, 1)
+
+	c.Add(models.Order{OrderUID: "a"})
+	c.Add(models.Order{OrderUID: "b"})
+	c.Add(models.Order{OrderUID: "a", TrackNumber: "updated"})
+
+	if _, ok := c.Get("b"); !ok {
+		t.Error("Expected b to stay cached after updating a")
+	}
+	order, ok := c.Get("a")
+	if !ok || order.TrackNumber != "updated" {
+		t.Errorf("Expected updated a, got %+v", order)
+	}
+}
+
+func TestCache_ShardsShareCapacity(t *testing.T) {
+	c := newTestCache(t, 100, 8)
+
+	for i := 0; i < 1000; i++ {
+		c.Add(models.Order{OrderUID: fmt.Sprintf("order-%d", i)})
+	}
+
+	stats := c.GetStats()
+	if stats.Count > stats.MaxSize || stats.MaxSize != 100 {
+		t.Errorf("Expected at most 100 cached orders, got %d/%d", stats.Count, stats.MaxSize)
+	}
+}
+
+func TestCache_ConcurrentAccess(t *testing.T) {
+	c := newTestCache(t, 50, 4)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				uid := fmt.Sprintf("order-%d", (i*7+w)%200)
+				if i%5 == 0 {
+					c.Add(models.Order{OrderUID: uid})
+				} else {
+					c.Get(uid)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if count := c.GetStats().Count; count > 50 {
+		t.Errorf("Expected at most 50 cached orders, got %d", count)
+	}
+}
+
+func TestCache_ExpiredEntryNotServed(t *testing.T) {
+	c := newTestCache(t, 10, 1)
+
+	c.AddWithTTL(models.Order{OrderUID: "short"}, 20*time.Millisecond)
+	c.Add(models.Order{OrderUID: "long"})
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := c.Get("short"); ok {
+		t.Error("Expected expired order to be a miss before cleanup runs")
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Error("Expected order with default TTL to be cached")
+	}
+	if count := c.GetStats().Count; count != 1 {
+		t.Errorf("Expected expired order to be removed on read, got %d entries", count)
+	}
+}
+
+func TestCache_SlidingExpiration(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxSize:           10,
+		DefaultTTL:        60 * time.Millisecond,
+		CleanupInterval:   time.Hour,
+		SlidingExpiration: true,
+	})
+	defer c.StopCleanup()
+
+	c.Add(models.Order{OrderUID: "a"})
+	for i := 0; i < 4; i++ {
+		time.Sleep(30 * time.Millisecond)
+		if _, ok := c.Get("a"); !ok {
+			t.Fatalf("Expected read %d to extend expiration", i)
+		}
+	}
+
+	time.Sleep(90 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected order to expire without reads")
+	}
+}
+
+func TestCache_FinalStatusTTL(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		FinalStatusTTL:  20 * time.Millisecond,
+		FinalStatuses:   []int{300},
+	})
+	defer c.StopCleanup()
+
+	c.Add(models.Order{OrderUID: "final", Items: []models.Item{{Status: 300}}})
+	c.Add(models.Order{OrderUID: "active", Items: []models.Item{{Status: 300}, {Status: 202}}})
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := c.Get("final"); ok {
+		t.Error("Expected order in final status to expire with final_status_ttl")
+	}
+	if _, ok := c.Get("active"); !ok {
+		t.Error("Expected order with active items to use default TTL")
+	}
+}
+
+func TestCache_ByteBudget(t *testing.T) {
+	small := make([]models.Order, 3)
+	for i := range small {
+		small[i] = models.Order{OrderUID: fmt.Sprintf("small-%d", i), Items: make([]models.Item, 1)}
+	}
+	large := models.Order{OrderUID: "large", Items: make([]models.Item, 50)}
+	budget := estimateSize(large) + 2*estimateSize(small[0])
+
+	c := New(&config.CacheConfig{
+		MaxBytes:        budget,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		Shards:          1,
+	})
+	defer c.StopCleanup()
+
+	for _, o := range small {
+		c.Add(o)
+	}
+	if stats := c.GetStats(); stats.Count != 3 {
+		t.Fatalf("Expected 3 small orders to fit, got %d", stats.Count)
+	}
+
+	// Большой заказ вытесняет маленькие, пока не поместится в бюджет
+	c.Add(large)
+	stats := c.GetStats()
+	if _, ok := c.Get("large"); !ok {
+		t.Error("Expected large order to be cached")
+	}
+	if stats.UsedBytes > budget {
+		t.Errorf("Expected usage within budget %d, got %d", budget, stats.UsedBytes)
+	}
+	if stats.Count != 3 {
+		t.Errorf("Expected large order and two small ones, got %d entries", stats.Count)
+	}
+	if _, ok := c.Get("small-0"); ok {
+		t.Error("Expected oldest small order to be evicted")
+	}
+}
+
+func TestCache_ByteBudget_OversizedOrderNotCached(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxBytes:        4096,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		Shards:          1,
+	})
+	defer c.StopCleanup()
+
+	c.Add(models.Order{OrderUID: "small"})
+	c.Add(models.Order{OrderUID: "huge", Items: make([]models.Item, 1000)})
+
+	if _, ok := c.Get("huge"); ok {
+		t.Error("Expected order larger than the budget not to be cached")
+	}
+	if _, ok := c.Get("small"); !ok {
+		t.Error("Expected existing order to survive an oversized insert")
+	}
+}
+
+func TestCache_NegativeCacheBounded(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		NegativeTTL:     30 * time.Millisecond,
+		NegativeMaxSize: 2,
+	})
+	defer c.StopCleanup()
+
+	c.MarkMissing("a")
+	c.MarkMissing("b")
+	c.MarkMissing("c")
+
+	if c.IsMissing("a") {
+		t.Error("Expected oldest negative entry to be evicted")
+	}
+	if !c.IsMissing("b") || !c.IsMissing("c") {
+		t.Error("Expected newest negative entries to be kept")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if c.IsMissing("b") {
+		t.Error("Expected negative entry to expire")
+	}
+}
+
+func TestCache_NegativeCacheDefaultBound(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		NegativeTTL:     time.Hour,
+	})
+	defer c.StopCleanup()
+
+	for i := 0; i <= defaultNegativeMaxSize; i++ {
+		c.MarkMissing("missing-" + strconv.Itoa(i))
+	}
+
+	if c.IsMissing("missing-0") {
+		t.Error("Expected unset negative_max_size to fall back to the default bound")
+	}
+	if !c.IsMissing("missing-" + strconv.Itoa(defaultNegativeMaxSize)) {
+		t.Error("Expected newest negative entry to be kept")
+	}
+}
+
+func TestCache_StatsCounters(t *testing.T) {
+	c := newTestCache(t, 2, 1)
+
+	c.Add(models.Order{OrderUID: "a"})
+	c.Add(models.Order{OrderUID: "a", TrackNumber: "updated"})
+	c.Add(models.Order{OrderUID: "b"})
+	c.Add(models.Order{OrderUID: "c"})
+	c.AddWithTTL(models.Order{OrderUID: "short"}, time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+	c.Get("short")
+	c.Get("c")
+	c.Get("missing")
+
+	stats := c.GetStats()
+	want := Stats{
+		Hits:              1,
+		Misses:            2,
+		Inserts:           4,
+		Overwrites:        1,
+		CapacityEvictions: 2,
+		ExpiredEvictions:  1,
+	}
+	if stats.Hits != want.Hits || stats.Misses != want.Misses || stats.Inserts != want.Inserts ||
+		stats.Overwrites != want.Overwrites || stats.CapacityEvictions != want.CapacityEvictions ||
+		stats.ExpiredEvictions != want.ExpiredEvictions {
+		t.Errorf("Unexpected counters %+v", stats)
+	}
+	if stats.HitRatio < 0.33 || stats.HitRatio > 0.34 {
+		t.Errorf("Expected hit ratio 1/3, got %f", stats.HitRatio)
+	}
+	if stats.AvgGetLatency <= 0 {
+		t.Error("Expected average get latency to be measured")
+	}
+}
+
+func TestCache_DeleteFlushEntries(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		NegativeTTL:     time.Hour,
+		NegativeMaxSize: 10,
+	})
+	defer c.StopCleanup()
+
+	c.Add(models.Order{OrderUID: "a"})
+	time.Sleep(time.Millisecond)
+	c.Add(models.Order{OrderUID: "b"})
+	c.MarkMissing("c")
+
+	entries := c.Entries()
+	if len(entries) != 2 || entries[0].OrderUID != "a" || entries[1].OrderUID != "b" {
+		t.Fatalf("Expected entries a, b from oldest, got %+v", entries)
+	}
+	if entries[0].Age <= 0 || entries[0].LastAccess.IsZero() || entries[0].ExpiresAt.IsZero() {
+		t.Errorf("Expected age, last access and expiration, got %+v", entries[0])
+	}
+
+	if !c.Delete("a") || c.Delete("a") {
+		t.Error("Expected a to be deleted exactly once")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected deleted order to be a miss")
+	}
+
+	c.Flush()
+	if stats := c.GetStats(); stats.Count != 0 {
+		t.Errorf("Expected empty cache after flush, got %d", stats.Count)
+	}
+	if c.IsMissing("c") {
+		t.Error("Expected flush to remove missing marks")
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("order-%d", i)
+	}
+	return keys
+}
+
+// BenchmarkCache_Add вставляет новые заказы в заполненный кэш, так что каждая
+// вставка вытесняет элемент. Время на операцию не должно расти с размером кэша
+func BenchmarkCache_Add(b *testing.B) {
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			c := newTestCache(b, size, defaultShards)
+			keys := benchmarkKeys(size * 2)
+			for _, k := range keys[:size] {
+				c.Add(models.Order{OrderUID: k})
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Add(models.Order{OrderUID: keys[i%len(keys)]})
+			}
+		})
+	}
+}
+
+func BenchmarkCache_Get(b *testing.B) {
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			c := newTestCache(b, size, defaultShards)
+			keys := benchmarkKeys(size)
+			for _, k := range keys {
+				c.Add(models.Order{OrderUID: k})
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Get(keys[i%len(keys)])
+			}
+		})
+	}
+}
+
+// BenchmarkCache_Concurrent моделирует рабочую нагрузку: конкурентные чтения из
+// OrderHandler и каждая десятая операция — запись из консюмера. Вариант с одним
+// шардом показывает, во что обходится глобальная блокировка
+func BenchmarkCache_Concurrent(b *testing.B) {
+	const size = 10_000
+
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := newTestCache(b, size, shards)
+			keys := benchmarkKeys(size * 2)
+			for _, k := range keys[:size] {
+				c.Add(models.Order{OrderUID: k})
+			}
+
+			var counter atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := counter.Add(1)
+					key := keys[n%uint64(len(keys))]
+					if n%10 == 0 {
+						c.Add(models.Order{OrderUID: key})
+					} else {
+						c.Get(key)
+					}
+				}
+			})
+		})
+	}
+}
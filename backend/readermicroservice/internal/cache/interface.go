@@ -1,15 +1,50 @@
 package cache
 
-import "readermicroservice/internal/models"
+import (
+	"context"
+	"time"
 
+	"readermicroservice/internal/models"
+)
+
+// OrderCache — кэш заказов поверх БД (write-through).
+// Кэш отражает только закоммиченное состояние database.Database: заказ попадает
+// в кэш либо после успешного Insert, либо при загрузке из БД. Поэтому всё, что
+// отдается из кэша, можно найти в БД и после перезапуска сервиса.
 type OrderCache interface {
+	// Add добавляет или обновляет заказ. Вызывается только после того,
+	// как эта версия заказа успешно записана в БД
 	Add(order models.Order)
+	// AddWithTTL работает как Add, но с собственным сроком жизни элемента
+	AddWithTTL(order models.Order, ttl time.Duration)
+	// AddIfAbsent добавляет заказ, прочитанный из БД, если в кэше еще нет его версии
+	AddIfAbsent(order models.Order) bool
 	Get(orderUID string) (models.Order, bool)
-	GetStats() (int, int, []string)
+	// MarkMissing запоминает, что заказа нет в БД; отметка снимается при Add
+	MarkMissing(orderUID string)
+	// IsMissing сообщает, известно ли, что заказа нет в БД
+	IsMissing(orderUID string) bool
+	// GetMany ищет заказы по списку order_uid за одно обращение к хранилищу. orders —
+	// найденные заказы, missing — order_uid, про которые известно, что их нет в БД
+	GetMany(orderUIDs []string) (orders map[string]models.Order, missing map[string]bool)
+	// AddManyIfAbsent работает как AddIfAbsent для каждого заказа
+	AddManyIfAbsent(orders []models.Order)
+	// MarkManyMissing работает как MarkMissing для каждого order_uid
+	MarkManyMissing(orderUIDs []string)
+	// Delete удаляет заказ и отметку о его отсутствии; возвращает true, если заказ был в кэше
+	Delete(orderUID string) bool
+	// Flush удаляет все заказы и отметки об отсутствии
+	Flush()
+	// Entries возвращает сведения о закэшированных заказах без самих заказов
+	Entries() []EntryInfo
+	GetStats() Stats
 	StopCleanup()
+	// ResetDB заменяет содержимое кэша самыми новыми заказами из БД
 	ResetDB(db OrderDatabase)
 }
 
+// OrderDatabase — источник данных для прогрева кэша
 type OrderDatabase interface {
-	GetAll() ([]models.Order, error)
+	// IterateRecent обходит не более limit самых новых заказов, пока fn возвращает true
+	IterateRecent(ctx context.Context, limit int, fn func(models.Order) bool) error
 }
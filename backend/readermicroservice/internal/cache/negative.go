@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultNegativeMaxSize — предел числа отметок об отсутствии, если он не задан в
+// конфигурации. Неограниченным негативный кэш не бывает: один POST /orders:batchGet
+// может добавить в него до тысячи произвольных order_uid
+const defaultNegativeMaxSize = 10000
+
+type negativeItem struct {
+	orderUID  string
+	expiresAt time.Time
+}
+
+// negativeCache хранит order_uid, которых нет в БД, чтобы повторные запросы
+// несуществующих заказов не доходили до БД. Размер ограничен: при переполнении
+// вытесняется самая старая запись
+type negativeCache struct {
+	mu       sync.Mutex
+	elements map[string]*list.Element
+	order    *list.List // элементы *negativeItem в порядке добавления, в начале — новые
+	ttl      time.Duration
+	maxSize  int
+}
+
+func newNegativeCache(ttl time.Duration, maxSize int) *negativeCache {
+	if maxSize <= 0 {
+		maxSize = defaultNegativeMaxSize
+	}
+	return &negativeCache{
+		elements: make(map[string]*list.Element),
+		order:    list.New(),
+		ttl:      ttl,
+		maxSize:  maxSize,
+	}
+}
+
+// add запоминает, что заказа нет в БД
+func (n *negativeCache) add(orderUID string, now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if el, exists := n.elements[orderUID]; exists {
+		el.Value.(*negativeItem).expiresAt = now.Add(n.ttl)
+		n.order.MoveToFront(el)
+		return
+	}
+
+	if len(n.elements) >= n.maxSize {
+		n.removeElement(n.order.Back())
+	}
+
+	n.elements[orderUID] = n.order.PushFront(&negativeItem{
+		orderUID:  orderUID,
+		expiresAt: now.Add(n.ttl),
+	})
+}
+
+// contains сообщает, известно ли, что заказа нет в БД. Истекшая запись удаляется
+func (n *negativeCache) contains(orderUID string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	el, exists := n.elements[orderUID]
+	if !exists {
+		return false
+	}
+	if !now.Before(el.Value.(*negativeItem).expiresAt) {
+		n.removeElement(el)
+		return false
+	}
+	return true
+}
+
+// remove забывает запись о заказе, например когда заказ появился в БД
+func (n *negativeCache) remove(orderUID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if el, exists := n.elements[orderUID]; exists {
+		n.removeElement(el)
+	}
+}
+
+// reset удаляет все записи
+func (n *negativeCache) reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.elements = make(map[string]*list.Element)
+	n.order.Init()
+}
+
+func (n *negativeCache) removeElement(el *list.Element) {
+	n.order.Remove(el)
+	delete(n.elements, el.Value.(*negativeItem).orderUID)
+}
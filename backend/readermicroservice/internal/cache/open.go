@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"readermicroservice/internal/config"
+)
+
+// Open создает кэш заказов выбранного в конфигурации типа: в памяти процесса
+// или общий кэш в Redis, при необходимости с локальным кэшем первого уровня
+func Open(conf *config.CacheConfig) (OrderCache, error) {
+	if conf.Backend != config.CacheBackendRedis {
+		return New(conf), nil
+	}
+
+	l2, err := NewRedis(conf)
+	if err != nil {
+		return nil, err
+	}
+	if !conf.Redis.L1 || conf.Redis.L1TTL <= 0 {
+		return l2, nil
+	}
+
+	// L1 использует те же ограничения размера, что и кэш в памяти,
+	// а отметки об отсутствии держит не дольше l1_ttl
+	l1Conf := *conf
+	l1Conf.DefaultTTL = conf.Redis.L1TTL
+	l1Conf.FinalStatusTTL = 0
+	l1Conf.SlidingExpiration = false
+	l1Conf.NegativeTTL = min(conf.NegativeTTL, conf.Redis.L1TTL)
+	return NewTiered(New(&l1Conf), l2, conf.Redis.L1TTL), nil
+}
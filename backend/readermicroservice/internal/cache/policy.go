@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"container/list"
+
+	"readermicroservice/internal/config"
+)
+
+// evictionPolicy решает, какой элемент шарда вытеснить, и хранит элементы в своих
+// списках. access вызывается под блокировкой шарда на чтение и должна менять
+// только атомарные поля, остальные методы — под блокировкой на запись.
+// Шард вытесняет элементы до вставки нового, поэтому новый элемент не может
+// оказаться жертвой собственной вставки
+type evictionPolicy interface {
+	// insert добавляет элемент. recent == false — элемент добавляется при прогреве
+	// или из снимка и должен вытесняться раньше добавленных при работе
+	insert(e *entry, recent bool)
+	// access отмечает попадание в кэш
+	access(e *entry)
+	// remove забывает удаленный из шарда элемент
+	remove(e *entry)
+	// victim возвращает элемент, который нужно вытеснить, или nil, если элементов нет
+	victim() *entry
+	// walk обходит элементы от наиболее ценных к наименее ценным
+	walk(fn func(e *entry))
+	reset()
+}
+
+// newPolicy создает политику вытеснения шарда; maxSize — емкость шарда, 0 — не ограничена
+func newPolicy(name string, maxSize int) evictionPolicy {
+	switch name {
+	case config.EvictionLFU:
+		return &lfuPolicy{list: list.New()}
+	case config.EvictionTinyLFU:
+		return newTinyLFUPolicy(maxSize)
+	default:
+		return &lruPolicy{list: list.New()}
+	}
+}
+
+// clockVictim возвращает элемент с конца списка, давая второй шанс элементам,
+// к которым был доступ с прошлого прохода (алгоритм CLOCK)
+func clockVictim(l *list.List) *entry {
+	for el := l.Back(); el != nil; el = l.Back() {
+		e := el.Value.(*entry)
+		if e.referenced.Swap(false) {
+			l.MoveToFront(el)
+			continue
+		}
+		return e
+	}
+	return nil
+}
+
+func walkList(l *list.List, fn func(e *entry)) {
+	for el := l.Front(); el != nil; el = el.Next() {
+		fn(el.Value.(*entry))
+	}
+}
+
+// lruPolicy — приближенный LRU: при попадании выставляется флаг referenced,
+// а перестановка в списке откладывается до вытеснения
+type lruPolicy struct {
+	list *list.List // в начале — недавно добавленные или использованные
+}
+
+func (p *lruPolicy) insert(e *entry, recent bool) {
+	if recent {
+		e.element = p.list.PushFront(e)
+	} else {
+		e.element = p.list.PushBack(e)
+	}
+}
+
+func (p *lruPolicy) access(e *entry)        { e.referenced.Store(true) }
+func (p *lruPolicy) remove(e *entry)        { p.list.Remove(e.element) }
+func (p *lruPolicy) victim() *entry         { return clockVictim(p.list) }
+func (p *lruPolicy) walk(fn func(e *entry)) { walkList(p.list, fn) }
+func (p *lruPolicy) reset()                 { p.list.Init() }
+
+// maxFrequency — предел счетчика обращений LFU, ограничивающий длину прохода вытеснения
+const maxFrequency = 15
+
+// lfuPolicy — LFU в стиле GCLOCK: у элемента есть счетчик обращений, проход
+// вытеснения уменьшает счетчики и вытесняет первый элемент с нулевым счетчиком.
+// Уменьшение счетчиков старит частоты, поэтому когда-то популярные заказы
+// не остаются в кэше навсегда
+type lfuPolicy struct {
+	list *list.List
+}
+
+func (p *lfuPolicy) insert(e *entry, recent bool) {
+	e.frequency.Store(0)
+	if recent {
+		e.element = p.list.PushFront(e)
+	} else {
+		e.element = p.list.PushBack(e)
+	}
+}
+
+func (p *lfuPolicy) access(e *entry) {
+	for {
+		f := e.frequency.Load()
+		if f >= maxFrequency || e.frequency.CompareAndSwap(f, f+1) {
+			return
+		}
+	}
+}
+
+func (p *lfuPolicy) remove(e *entry) { p.list.Remove(e.element) }
+
+func (p *lfuPolicy) victim() *entry {
+	for el := p.list.Back(); el != nil; el = p.list.Back() {
+		e := el.Value.(*entry)
+		if f := e.frequency.Load(); f > 0 {
+			e.frequency.CompareAndSwap(f, f-1)
+			p.list.MoveToFront(el)
+			continue
+		}
+		return e
+	}
+	return nil
+}
+
+func (p *lfuPolicy) walk(fn func(e *entry)) { walkList(p.list, fn) }
+func (p *lfuPolicy) reset()                 { p.list.Init() }
+
+// windowPercent — доля окна W-TinyLFU от количества элементов шарда
+const windowPercent = 1
+
+// tinyLFUPolicy — W-TinyLFU с сегментами на CLOCK. Новые элементы попадают в
+// маленькое окно; кандидат на выход из окна переходит в основной сегмент, только
+// если по оценке частоты обращений (count-min sketch) он популярнее жертвы
+// основного сегмента. Поэтому разовый проход по множеству заказов (например,
+// выгрузка из инструментов поддержки) вытесняет только окно, а не горячие заказы
+type tinyLFUPolicy struct {
+	window *list.List
+	main   *list.List
+	sketch *frequencySketch
+}
+
+func newTinyLFUPolicy(maxSize int) *tinyLFUPolicy {
+	return &tinyLFUPolicy{
+		window: list.New(),
+		main:   list.New(),
+		sketch: newFrequencySketch(maxSize),
+	}
+}
+
+func (p *tinyLFUPolicy) insert(e *entry, recent bool) {
+	p.sketch.increment(e.order.OrderUID)
+	e.inMain = !recent
+	if recent {
+		e.element = p.window.PushFront(e)
+	} else {
+		e.element = p.main.PushBack(e)
+	}
+}
+
+func (p *tinyLFUPolicy) access(e *entry) {
+	e.referenced.Store(true)
+	p.sketch.increment(e.order.OrderUID)
+}
+
+func (p *tinyLFUPolicy) remove(e *entry) {
+	if e.inMain {
+		p.main.Remove(e.element)
+	} else {
+		p.window.Remove(e.element)
+	}
+}
+
+func (p *tinyLFUPolicy) victim() *entry {
+	windowMax := max(1, (p.window.Len()+p.main.Len())*windowPercent/100)
+
+	// Элементы, добавленные, пока в шарде было место, переходят в основной
+	// сегмент без отбора; соревнуется только последний вышедший из окна
+	for p.window.Len() > windowMax+1 {
+		p.promote(p.window.Back().Value.(*entry))
+	}
+
+	if p.window.Len() <= windowMax && p.main.Len() > 0 {
+		return clockVictim(p.main)
+	}
+
+	candidate := clockVictim(p.window)
+	if candidate == nil || p.main.Len() == 0 {
+		return candidate
+	}
+
+	// Кандидат из окна переходит в основной сегмент и соревнуется с его жертвой
+	mainVictim := clockVictim(p.main)
+	p.promote(candidate)
+
+	if p.sketch.estimate(candidate.order.OrderUID) > p.sketch.estimate(mainVictim.order.OrderUID) {
+		return mainVictim
+	}
+	return candidate
+}
+
+// promote переносит элемент из окна в начало основного сегмента
+func (p *tinyLFUPolicy) promote(e *entry) {
+	p.window.Remove(e.element)
+	e.element = p.main.PushFront(e)
+	e.inMain = true
+}
+
+func (p *tinyLFUPolicy) walk(fn func(e *entry)) {
+	walkList(p.window, fn)
+	walkList(p.main, fn)
+}
+
+func (p *tinyLFUPolicy) reset() {
+	p.window.Init()
+	p.main.Init()
+	p.sketch.reset()
+}
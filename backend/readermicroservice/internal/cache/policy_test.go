@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+//go:generate go run testdata/gen_trace.go
+
+// traceCacheSize — емкость кэша при проигрывании трассы, около 2% различных заказов в ней
+const traceCacheSize = 500
+
+// loadTrace читает трассу обращений testdata/access_trace.txt.gz: по одному order_uid
+// на строку, строки с # — комментарии. Трасса синтетическая и повторяет профиль
+// нагрузки сервиса: обращения к заказам по закону Ципфа, прерываемые
+// последовательными проходами по редко запрашиваемым заказам. Ее генерирует
+// testdata/gen_trace.go (go generate)
+func loadTrace(tb testing.TB) []string {
+	tb.Helper()
+
+	f, err := os.Open("testdata/access_trace.txt.gz")
+	if err != nil {
+		tb.Fatalf("Failed to open trace: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		tb.Fatalf("Failed to read trace: %v", err)
+	}
+
+	var trace []string
+	sc := bufio.NewScanner(gz)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" && !strings.HasPrefix(line, "#") {
+			trace = append(trace, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		tb.Fatalf("Failed to read trace: %v", err)
+	}
+	return trace
+}
+
+// replayTrace проигрывает трассу так же, как OrderHandler: при промахе заказ
+// добавляется в кэш. Возвращает долю попаданий
+func replayTrace(trace []string, eviction string) float64 {
+	c := New(&config.CacheConfig{
+		MaxSize:         traceCacheSize,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		Shards:          1,
+		Eviction:        eviction,
+	})
+	defer c.StopCleanup()
+
+	hits := 0
+	for _, uid := range trace {
+		if _, ok := c.Get(uid); ok {
+			hits++
+			continue
+		}
+		c.AddIfAbsent(models.Order{OrderUID: uid})
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+func TestEvictionPolicies_RespectCapacity(t *testing.T) {
+	for _, eviction := range []string{config.EvictionLRU, config.EvictionLFU, config.EvictionTinyLFU} {
+		t.Run(eviction, func(t *testing.T) {
+			c := New(&config.CacheConfig{
+				MaxSize:         50,
+				DefaultTTL:      time.Hour,
+				CleanupInterval: time.Hour,
+				Shards:          2,
+				Eviction:        eviction,
+			})
+			defer c.StopCleanup()
+
+			for i := 0; i < 1000; i++ {
+				uid := fmt.Sprintf("order-%d", i%300)
+				if _, ok := c.Get(uid); !ok {
+					c.Add(models.Order{OrderUID: uid})
+				}
+			}
+
+			stats := c.GetStats()
+			if stats.Count > 50 {
+				t.Errorf("Expected at most 50 cached orders, got %d", stats.Count)
+			}
+			if stats.Count < 25 {
+				t.Errorf("Expected cache to stay filled, got %d orders", stats.Count)
+			}
+		})
+	}
+}
+
+func TestEvictionTinyLFU_ScanResistant(t *testing.T) {
+	c := New(&config.CacheConfig{
+		MaxSize:         100,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		Shards:          1,
+		Eviction:        config.EvictionTinyLFU,
+	})
+	defer c.StopCleanup()
+
+	// Горячие заказы запрашиваются многократно
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			uid := fmt.Sprintf("hot-%d", i)
+			if _, ok := c.Get(uid); !ok {
+				c.Add(models.Order{OrderUID: uid})
+			}
+		}
+	}
+	// Разовый проход по большому числу заказов не должен вытеснить горячие
+	for i := 0; i < 1000; i++ {
+		c.Add(models.Order{OrderUID: fmt.Sprintf("scan-%d", i)})
+	}
+
+	kept := 0
+	for i := 0; i < 50; i++ {
+		if _, ok := c.Get(fmt.Sprintf("hot-%d", i)); ok {
+			kept++
+		}
+	}
+	if kept < 45 {
+		t.Errorf("Expected hot orders to survive the scan, %d/50 kept", kept)
+	}
+}
+
+func TestEvictionPolicies_TraceHitRatio(t *testing.T) {
+	trace := loadTrace(t)
+
+	lru := replayTrace(trace, config.EvictionLRU)
+	tinyLFU := replayTrace(trace, config.EvictionTinyLFU)
+	t.Logf("hit ratio: lru %.3f, tinylfu %.3f", lru, tinyLFU)
+
+	if tinyLFU <= lru {
+		t.Errorf("Expected tinylfu to beat lru on the trace, got %.3f <= %.3f", tinyLFU, lru)
+	}
+}
+
+// BenchmarkEviction_HitRatio проигрывает трассу обращений для каждой политики и
+// сообщает долю попаданий метрикой hit-ratio
+func BenchmarkEviction_HitRatio(b *testing.B) {
+	trace := loadTrace(b)
+
+	for _, eviction := range []string{config.EvictionLRU, config.EvictionLFU, config.EvictionTinyLFU} {
+		b.Run(eviction, func(b *testing.B) {
+			var ratio float64
+			for i := 0; i < b.N; i++ {
+				ratio = replayTrace(trace, eviction)
+			}
+			b.ReportMetric(ratio, "hit-ratio")
+		})
+	}
+}
@@ -0,0 +1,510 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+// redisWarmupBatch — сколько заказов отправляется в Redis одним конвейером при прогреве
+const redisWarmupBatch = 100
+
+// redisEntry — значение, которое хранится в Redis по ключу заказа
+type redisEntry struct {
+	Order     models.Order  `json:"order"`
+	CreatedAt time.Time     `json:"created_at"`
+	TTL       time.Duration `json:"ttl,omitempty"`
+}
+
+// RedisCache реализует OrderCache поверх Redis-совместимого хранилища, общего для
+// всех реплик сервиса. Сроки жизни заказов и отметок об отсутствии отдаются Redis
+// (SET ... PX), поэтому фоновая очистка не нужна. Ошибки хранилища логируются,
+// а чтение при ошибке считается промахом
+type RedisCache struct {
+	client      *respClient
+	prefix      string
+	ttl         ttlPolicy
+	sliding     bool
+	negativeTTL time.Duration
+	maxSize     int
+	stats       counters
+}
+
+// NewRedis создает кэш в Redis и проверяет соединение
+func NewRedis(conf *config.CacheConfig) (*RedisCache, error) {
+	rc := conf.Redis
+	c := &RedisCache{
+		client:      newRESPClient(rc.Addr, rc.Password, rc.DB, rc.PoolSize, rc.Timeout),
+		prefix:      rc.KeyPrefix,
+		ttl:         newTTLPolicy(conf),
+		sliding:     conf.SlidingExpiration,
+		negativeTTL: conf.NegativeTTL,
+		maxSize:     conf.MaxSize,
+	}
+
+	if _, err := c.client.do("PING"); err != nil {
+		c.client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+	return c, nil
+}
+
+func (c *RedisCache) orderKey(orderUID string) string {
+	return c.prefix + "order:" + orderUID
+}
+
+func (c *RedisCache) missingKey(orderUID string) string {
+	return c.prefix + "missing:" + orderUID
+}
+
+// setCommand собирает команду SET для заказа; при onlyIfAbsent добавляется NX
+func (c *RedisCache) setCommand(order models.Order, ttl time.Duration, onlyIfAbsent bool) ([]string, error) {
+	value, err := json.Marshal(redisEntry{Order: order, CreatedAt: time.Now(), TTL: ttl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order %s: %w", order.OrderUID, err)
+	}
+
+	cmd := []string{"SET", c.orderKey(order.OrderUID), string(value)}
+	if ttl > 0 {
+		cmd = append(cmd, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	if onlyIfAbsent {
+		cmd = append(cmd, "NX")
+	}
+	return cmd, nil
+}
+
+// Add добавляет заказ или заменяет его версию в Redis
+func (c *RedisCache) Add(order models.Order) {
+	c.AddWithTTL(order, c.ttl.ttlFor(order))
+}
+
+// AddWithTTL добавляет заказ с собственным сроком жизни; ttl <= 0 — без истечения
+func (c *RedisCache) AddWithTTL(order models.Order, ttl time.Duration) {
+	c.set(order, ttl, false)
+}
+
+// AddIfAbsent добавляет заказ, только если его еще нет в Redis
+func (c *RedisCache) AddIfAbsent(order models.Order) bool {
+	return c.set(order, c.ttl.ttlFor(order), true)
+}
+
+// set записывает заказ и снимает отметку о его отсутствии одним конвейером
+func (c *RedisCache) set(order models.Order, ttl time.Duration, onlyIfAbsent bool) bool {
+	cmd, err := c.setCommand(order, ttl, onlyIfAbsent)
+	if err != nil {
+		config.RLogger.Printf("Redis cache: %v", err)
+		return false
+	}
+
+	replies, err := c.client.pipeline([][]string{cmd, {"DEL", c.missingKey(order.OrderUID)}})
+	if err == nil {
+		err = firstError(replies)
+	}
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error storing order %s: %v", order.OrderUID, err)
+		return false
+	}
+
+	// SET ... NX возвращает пустой ответ, если ключ уже существует
+	if replies[0] == nil {
+		return false
+	}
+	c.stats.inserts.Add(1)
+	return true
+}
+
+// Get получает заказ из Redis. При скользящем сроке жизни он продлевается
+func (c *RedisCache) Get(orderUID string) (models.Order, bool) {
+	start := time.Now()
+	order, ok := c.get(orderUID)
+	c.stats.getTime.Add(int64(time.Since(start)))
+	if ok {
+		c.stats.hits.Add(1)
+	} else {
+		c.stats.misses.Add(1)
+	}
+	return order, ok
+}
+
+func (c *RedisCache) get(orderUID string) (models.Order, bool) {
+	reply, err := c.client.do("GET", c.orderKey(orderUID))
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error getting order %s: %v", orderUID, err)
+		return models.Order{}, false
+	}
+	e, ok := decodeEntry(orderUID, reply)
+	if !ok {
+		return models.Order{}, false
+	}
+
+	if c.sliding && e.TTL > 0 {
+		if _, err := c.client.do("PEXPIRE", c.orderKey(orderUID), strconv.FormatInt(e.TTL.Milliseconds(), 10)); err != nil {
+			config.RLogger.Printf("Redis cache: error extending TTL of order %s: %v", orderUID, err)
+		}
+	}
+	return e.Order, true
+}
+
+// decodeEntry разбирает ответ GET/MGET; пустой ответ — промах
+func decodeEntry(orderUID string, reply interface{}) (redisEntry, bool) {
+	value, ok := reply.([]byte)
+	if !ok {
+		return redisEntry{}, false
+	}
+
+	var e redisEntry
+	if err := json.Unmarshal(value, &e); err != nil {
+		config.RLogger.Printf("Redis cache: error decoding order %s: %v", orderUID, err)
+		return redisEntry{}, false
+	}
+	return e, true
+}
+
+// GetMany читает заказы и отметки об отсутствии одной командой MGET.
+// При скользящем сроке жизни найденные заказы продлеваются одним конвейером
+func (c *RedisCache) GetMany(orderUIDs []string) (map[string]models.Order, map[string]bool) {
+	orders := make(map[string]models.Order, len(orderUIDs))
+	missing := make(map[string]bool)
+	if len(orderUIDs) == 0 {
+		return orders, missing
+	}
+
+	start := time.Now()
+	defer func() {
+		c.stats.getTime.Add(int64(time.Since(start)))
+		c.stats.hits.Add(int64(len(orders)))
+		c.stats.misses.Add(int64(len(orderUIDs) - len(orders)))
+	}()
+
+	cmd := make([]string, 0, 1+2*len(orderUIDs))
+	cmd = append(cmd, "MGET")
+	for _, uid := range orderUIDs {
+		cmd = append(cmd, c.orderKey(uid))
+	}
+	if c.negativeTTL > 0 {
+		for _, uid := range orderUIDs {
+			cmd = append(cmd, c.missingKey(uid))
+		}
+	}
+
+	reply, err := c.client.do(cmd...)
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error getting %d orders: %v", len(orderUIDs), err)
+		return orders, missing
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != len(cmd)-1 {
+		config.RLogger.Printf("Redis cache: unexpected MGET reply %v", reply)
+		return orders, missing
+	}
+
+	var extend [][]string
+	for i, uid := range orderUIDs {
+		e, ok := decodeEntry(uid, values[i])
+		if !ok {
+			if c.negativeTTL > 0 && values[len(orderUIDs)+i] != nil {
+				missing[uid] = true
+			}
+			continue
+		}
+		orders[uid] = e.Order
+		if c.sliding && e.TTL > 0 {
+			extend = append(extend, []string{"PEXPIRE", c.orderKey(uid), strconv.FormatInt(e.TTL.Milliseconds(), 10)})
+		}
+	}
+
+	if len(extend) > 0 {
+		if _, err := c.client.pipeline(extend); err != nil {
+			config.RLogger.Printf("Redis cache: error extending TTL of %d orders: %v", len(extend), err)
+		}
+	}
+	return orders, missing
+}
+
+// AddManyIfAbsent добавляет заказы, которых еще нет в Redis, одним конвейером
+func (c *RedisCache) AddManyIfAbsent(orders []models.Order) {
+	c.addManyIfAbsent(orders)
+}
+
+// addManyIfAbsent возвращает заказы, которые были записаны
+func (c *RedisCache) addManyIfAbsent(orders []models.Order) []models.Order {
+	cmds := make([][]string, 0, 2*len(orders))
+	pending := make([]models.Order, 0, len(orders))
+	for _, order := range orders {
+		cmd, err := c.setCommand(order, c.ttl.ttlFor(order), true)
+		if err != nil {
+			config.RLogger.Printf("Redis cache: %v", err)
+			continue
+		}
+		cmds = append(cmds, cmd, []string{"DEL", c.missingKey(order.OrderUID)})
+		pending = append(pending, order)
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	replies, err := c.client.pipeline(cmds)
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error storing %d orders: %v", len(pending), err)
+		return nil
+	}
+
+	var added []models.Order
+	for i, order := range pending {
+		// SET ... NX возвращает пустой ответ, если ключ уже существует
+		switch reply := replies[2*i].(type) {
+		case nil:
+		case error:
+			config.RLogger.Printf("Redis cache: error storing order %s: %v", order.OrderUID, reply)
+		default:
+			added = append(added, order)
+		}
+	}
+	c.stats.inserts.Add(int64(len(added)))
+	return added
+}
+
+// markMissingScript ставит отметки об отсутствии только тем заказам, которых нет в Redis.
+// Проверка и запись выполняются атомарно, поэтому заказ, записанный консьюмером
+// одновременно с промахом, не может получить отметку после записи.
+// KEYS — пары (ключ заказа, ключ отметки), ARGV[1] — срок жизни отметки в мс
+const markMissingScript = `for i = 1, #KEYS, 2 do
+  if redis.call('EXISTS', KEYS[i]) == 0 then
+    redis.call('SET', KEYS[i + 1], '1', 'PX', ARGV[1])
+  end
+end
+return 0`
+
+// MarkMissing запоминает на negative_ttl, что заказа нет в БД
+func (c *RedisCache) MarkMissing(orderUID string) {
+	c.MarkManyMissing([]string{orderUID})
+}
+
+// MarkManyMissing ставит отметки об отсутствии одним вызовом markMissingScript
+func (c *RedisCache) MarkManyMissing(orderUIDs []string) {
+	if c.negativeTTL <= 0 || len(orderUIDs) == 0 {
+		return
+	}
+
+	cmd := make([]string, 0, 4+2*len(orderUIDs))
+	cmd = append(cmd, "EVAL", markMissingScript, strconv.Itoa(2*len(orderUIDs)))
+	for _, uid := range orderUIDs {
+		cmd = append(cmd, c.orderKey(uid), c.missingKey(uid))
+	}
+	cmd = append(cmd, strconv.FormatInt(c.negativeTTL.Milliseconds(), 10))
+
+	if _, err := c.client.do(cmd...); err != nil {
+		config.RLogger.Printf("Redis cache: error marking %d orders missing: %v", len(orderUIDs), err)
+	}
+}
+
+// IsMissing сообщает, известно ли, что заказа нет в БД
+func (c *RedisCache) IsMissing(orderUID string) bool {
+	if c.negativeTTL <= 0 {
+		return false
+	}
+
+	reply, err := c.client.do("EXISTS", c.missingKey(orderUID))
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error checking missing order %s: %v", orderUID, err)
+		return false
+	}
+	n, _ := reply.(int64)
+	return n > 0
+}
+
+// scanKeys возвращает все ключи, подходящие под шаблон, обходя их командой SCAN
+func (c *RedisCache) scanKeys(pattern string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := c.client.do("SCAN", cursor, "MATCH", pattern, "COUNT", "1000")
+		if err != nil {
+			return keys, fmt.Errorf("failed to scan keys: %w", err)
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return keys, fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		next, _ := parts[0].([]byte)
+		batch, _ := parts[1].([]interface{})
+		for _, k := range batch {
+			if key, ok := k.([]byte); ok {
+				keys = append(keys, string(key))
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+// Delete удаляет заказ и отметку о его отсутствии
+func (c *RedisCache) Delete(orderUID string) bool {
+	reply, err := c.client.do("DEL", c.orderKey(orderUID), c.missingKey(orderUID))
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error deleting order %s: %v", orderUID, err)
+		return false
+	}
+	n, _ := reply.(int64)
+	return n > 0
+}
+
+// Flush удаляет заказы и отметки об отсутствии. Ищутся только ключи вида
+// <prefix>order:* и <prefix>missing:*, поэтому остальные данные в Redis не трогаются,
+// даже если key_prefix пуст
+func (c *RedisCache) Flush() {
+	var keys []string
+	for _, pattern := range []string{c.orderKey("") + "*", c.missingKey("") + "*"} {
+		found, err := c.scanKeys(pattern)
+		if err != nil {
+			config.RLogger.Printf("Redis cache: %v", err)
+		}
+		keys = append(keys, found...)
+	}
+
+	for start := 0; start < len(keys); start += redisWarmupBatch {
+		end := min(start+redisWarmupBatch, len(keys))
+		if _, err := c.client.do(append([]string{"DEL"}, keys[start:end]...)...); err != nil {
+			config.RLogger.Printf("Redis cache: error flushing keys: %v", err)
+			return
+		}
+	}
+}
+
+// Entries возвращает сведения о заказах в Redis. Время последнего доступа Redis
+// не хранит, поэтому оно не заполняется
+func (c *RedisCache) Entries() []EntryInfo {
+	orderPrefix := c.orderKey("")
+	keys, err := c.scanKeys(orderPrefix + "*")
+	if err != nil {
+		config.RLogger.Printf("Redis cache: %v", err)
+	}
+
+	now := time.Now()
+	entries := make([]EntryInfo, 0, len(keys))
+	for start := 0; start < len(keys); start += redisWarmupBatch {
+		batch := keys[start:min(start+redisWarmupBatch, len(keys))]
+		cmds := make([][]string, 0, 2*len(batch))
+		for _, key := range batch {
+			cmds = append(cmds, []string{"GET", key}, []string{"PTTL", key})
+		}
+		replies, err := c.client.pipeline(cmds)
+		if err != nil {
+			config.RLogger.Printf("Redis cache: error reading entries: %v", err)
+			break
+		}
+
+		for i, key := range batch {
+			// Ключ мог истечь между SCAN и GET
+			value, ok := replies[2*i].([]byte)
+			if !ok {
+				continue
+			}
+			var e redisEntry
+			if err := json.Unmarshal(value, &e); err != nil {
+				continue
+			}
+			info := EntryInfo{
+				OrderUID:  strings.TrimPrefix(key, orderPrefix),
+				CreatedAt: e.CreatedAt,
+				Age:       now.Sub(e.CreatedAt),
+				Size:      int64(len(value)),
+			}
+			if ttl, _ := replies[2*i+1].(int64); ttl > 0 {
+				info.ExpiresAt = now.Add(time.Duration(ttl) * time.Millisecond)
+			}
+			entries = append(entries, info)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries
+}
+
+// GetStats возвращает счетчики этой реплики и размер базы Redis (DBSIZE). Число заказов
+// не считается: обход ключей нагружал бы общий Redis при каждом опросе, а DBSIZE
+// включает отметки об отсутствии и ключи других приложений, поэтому он отдается
+// отдельным полем RedisDBKeys. Вытеснения выполняет сам Redis и здесь не учитываются
+func (c *RedisCache) GetStats() Stats {
+	st := Stats{MaxSize: c.maxSize}
+
+	reply, err := c.client.do("DBSIZE")
+	if err != nil {
+		config.RLogger.Printf("Redis cache: error reading database size: %v", err)
+	}
+	st.RedisDBKeys, _ = reply.(int64)
+
+	c.stats.addTo(&st)
+	st.derive()
+	return st
+}
+
+// StopCleanup закрывает соединения с Redis
+func (c *RedisCache) StopCleanup() {
+	c.client.Close()
+}
+
+// ResetDB записывает в Redis самые новые заказы из БД (не больше max_size, если он задан).
+// Хранилище общее для всех реплик, поэтому существующие ключи не удаляются,
+// а загруженные заказы перезаписываются версией из БД
+func (c *RedisCache) ResetDB(db OrderDatabase) {
+	limit := c.maxSize
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
+
+	loaded := 0
+	batch := make([][]string, 0, redisWarmupBatch)
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		replies, err := c.client.pipeline(batch)
+		if err == nil {
+			err = firstError(replies)
+		}
+		if err != nil {
+			config.RLogger.Printf("Error loading data from DB to redis cache: %v", err)
+			return false
+		}
+		loaded += len(batch)
+		batch = batch[:0]
+		return true
+	}
+
+	err := db.IterateRecent(context.Background(), limit, func(order models.Order) bool {
+		cmd, err := c.setCommand(order, c.ttl.ttlFor(order), false)
+		if err != nil {
+			config.RLogger.Printf("Redis cache: %v", err)
+			return true
+		}
+		batch = append(batch, cmd)
+		if len(batch) < redisWarmupBatch {
+			return true
+		}
+		return flush()
+	})
+	if err != nil {
+		config.RLogger.Printf("Error loading data from DB to redis cache: %v", err)
+		return
+	}
+	if !flush() {
+		return
+	}
+
+	config.RLogger.Printf("Redis cache warmed up with %d orders", loaded)
+}
@@ -0,0 +1,398 @@
+package cache
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+// fakeRedis — сервер, понимающий подмножество команд Redis, которое использует RedisCache
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	ln       net.Listener
+	commands map[string]int // сколько раз выполнена каждая команда
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeRedis{
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+		ln:       ln,
+		commands: make(map[string]int),
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		parts, _ := reply.([]interface{})
+		args := make([]string, len(parts))
+		for i, p := range parts {
+			b, _ := p.([]byte)
+			args[i] = string(b)
+		}
+		w.WriteString(f.exec(args))
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// live проверяет ключ с учетом срока жизни. Вызывается под f.mu
+func (f *fakeRedis) live(key string) bool {
+	if exp, ok := f.expires[key]; ok && !time.Now().Before(exp) {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	_, ok := f.values[key]
+	return ok
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commands[strings.ToUpper(args[0])]++
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		if !f.live(args[1]) {
+			return "$-1\r\n"
+		}
+		return bulk(f.values[args[1]])
+	case "MGET":
+		reply := "*" + strconv.Itoa(len(args)-1) + "\r\n"
+		for _, key := range args[1:] {
+			if f.live(key) {
+				reply += bulk(f.values[key])
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case "SET":
+		key := args[1]
+		var ttl time.Duration
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			case "NX":
+				nx = true
+			}
+		}
+		if nx && f.live(key) {
+			return "$-1\r\n"
+		}
+		f.values[key] = args[2]
+		delete(f.expires, key)
+		if ttl > 0 {
+			f.expires[key] = time.Now().Add(ttl)
+		}
+		return "+OK\r\n"
+	case "DEL", "EXISTS":
+		n := 0
+		for _, key := range args[1:] {
+			if f.live(key) {
+				n++
+				if strings.ToUpper(args[0]) == "DEL" {
+					delete(f.values, key)
+					delete(f.expires, key)
+				}
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "PEXPIRE":
+		if !f.live(args[1]) {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[2])
+		f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	case "PTTL":
+		if !f.live(args[1]) {
+			return ":-2\r\n"
+		}
+		exp, ok := f.expires[args[1]]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return ":" + strconv.FormatInt(time.Until(exp).Milliseconds(), 10) + "\r\n"
+	case "EVAL":
+		// Выполняется только markMissingScript
+		if args[1] != markMissingScript {
+			return "-ERR unknown script\r\n"
+		}
+		n, _ := strconv.Atoi(args[2])
+		keys, ttl := args[3:3+n], args[3+n]
+		for i := 0; i < len(keys); i += 2 {
+			if !f.live(keys[i]) {
+				ms, _ := strconv.Atoi(ttl)
+				f.values[keys[i+1]] = "1"
+				f.expires[keys[i+1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		return ":0\r\n"
+	case "DBSIZE":
+		n := 0
+		for key := range f.values {
+			if f.live(key) {
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SCAN":
+		// Все ключи возвращаются за один проход
+		prefix := strings.TrimSuffix(args[3], "*")
+		var keys []string
+		for key := range f.values {
+			if strings.HasPrefix(key, prefix) && f.live(key) {
+				keys = append(keys, bulk(key))
+			}
+		}
+		return "*2\r\n" + bulk("0") + "*" + strconv.Itoa(len(keys)) + "\r\n" + strings.Join(keys, "")
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func newTestRedisCache(t *testing.T, conf config.CacheConfig) *RedisCache {
+	t.Helper()
+	return connectTestRedis(t, startFakeRedis(t), conf)
+}
+
+func connectTestRedis(t *testing.T, f *fakeRedis, conf config.CacheConfig) *RedisCache {
+	t.Helper()
+
+	conf.Redis = config.RedisConfig{
+		Addr:      f.ln.Addr().String(),
+		KeyPrefix: "test:",
+		PoolSize:  2,
+		Timeout:   time.Second,
+	}
+	c, err := NewRedis(&conf)
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	t.Cleanup(c.StopCleanup)
+	return c
+}
+
+func TestRedisCache_AddGet(t *testing.T) {
+	f := startFakeRedis(t)
+	c := connectTestRedis(t, f, config.CacheConfig{DefaultTTL: time.Hour})
+
+	c.Add(models.Order{OrderUID: "a", TrackNumber: "track", Items: []models.Item{{ChrtID: 1}}})
+
+	order, ok := c.Get("a")
+	if !ok {
+		t.Fatal("Expected order to be cached")
+	}
+	if order.TrackNumber != "track" || len(order.Items) != 1 {
+		t.Errorf("Unexpected order %+v", order)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected unknown order to be a miss")
+	}
+	if stats := c.GetStats(); stats.Count != 0 || stats.RedisDBKeys != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected one key in redis, one hit and one miss, got %+v", stats)
+	}
+
+	// Статистика не обходит ключи: опрос не должен нагружать общий Redis
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.commands["SCAN"] != 0 {
+		t.Errorf("Expected stats without SCAN, got %v", f.commands)
+	}
+}
+
+func TestRedisCache_TTL(t *testing.T) {
+	c := newTestRedisCache(t, config.CacheConfig{DefaultTTL: time.Hour})
+
+	c.AddWithTTL(models.Order{OrderUID: "short"}, 20*time.Millisecond)
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := c.Get("short"); ok {
+		t.Error("Expected expired order to be a miss")
+	}
+}
+
+func TestRedisCache_AddIfAbsent(t *testing.T) {
+	c := newTestRedisCache(t, config.CacheConfig{DefaultTTL: time.Hour})
+
+	c.Add(models.Order{OrderUID: "a", TrackNumber: "fresh"})
+	if c.AddIfAbsent(models.Order{OrderUID: "a", TrackNumber: "stale"}) {
+		t.Error("Expected existing order not to be overwritten")
+	}
+	if order, _ := c.Get("a"); order.TrackNumber != "fresh" {
+		t.Errorf("Expected fresh order, got %q", order.TrackNumber)
+	}
+	if !c.AddIfAbsent(models.Order{OrderUID: "b"}) {
+		t.Error("Expected absent order to be added")
+	}
+}
+
+func TestRedisCache_NegativeCache(t *testing.T) {
+	c := newTestRedisCache(t, config.CacheConfig{DefaultTTL: time.Hour, NegativeTTL: time.Hour})
+
+	c.MarkMissing("a")
+	if !c.IsMissing("a") {
+		t.Fatal("Expected order to be marked missing")
+	}
+
+	c.Add(models.Order{OrderUID: "a"})
+	if c.IsMissing("a") {
+		t.Error("Expected adding the order to clear the missing mark")
+	}
+	c.MarkMissing("a")
+	if c.IsMissing("a") {
+		t.Error("Expected cached order not to be marked missing")
+	}
+}
+
+func TestRedisCache_ManyKeys(t *testing.T) {
+	f := startFakeRedis(t)
+	c := connectTestRedis(t, f, config.CacheConfig{DefaultTTL: time.Hour, NegativeTTL: time.Hour})
+
+	var orders []models.Order
+	var uids []string
+	for i := 0; i < 100; i++ {
+		uid := "o" + strconv.Itoa(i)
+		uids = append(uids, uid)
+		if i%2 == 0 {
+			orders = append(orders, models.Order{OrderUID: uid})
+		}
+	}
+	c.MarkMissing("o0")
+	c.AddManyIfAbsent(orders)
+	c.MarkManyMissing(uids[90:])
+
+	f.mu.Lock()
+	before := len(f.commands)
+	f.commands = make(map[string]int)
+	f.mu.Unlock()
+	if before == 0 {
+		t.Fatal("Expected commands to be counted")
+	}
+
+	found, missing := c.GetMany(uids)
+	if len(found) != 50 {
+		t.Errorf("Expected 50 orders, got %d", len(found))
+	}
+	if _, ok := found["o0"]; !ok {
+		t.Error("Expected adding the order to clear its missing mark")
+	}
+	// Отметки ставятся только заказам, которых нет в кэше
+	if len(missing) != 5 || !missing["o91"] || missing["o90"] {
+		t.Errorf("Expected odd uids from o91 to be missing, got %v", missing)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.commands) != 1 || f.commands["MGET"] != 1 {
+		t.Errorf("Expected a single MGET, got %v", f.commands)
+	}
+}
+
+func TestTiered_ServesFromL1(t *testing.T) {
+	l2 := newTestRedisCache(t, config.CacheConfig{DefaultTTL: time.Hour})
+	l1 := newTestCache(t, 10, 1)
+	c := NewTiered(l1, l2, 30*time.Millisecond)
+
+	c.Add(models.Order{OrderUID: "a", TrackNumber: "v1"})
+
+	// Другая реплика обновила заказ в Redis: L1 отдает старую версию, пока не истечет l1TTL
+	l2.Add(models.Order{OrderUID: "a", TrackNumber: "v2"})
+	if order, _ := c.Get("a"); order.TrackNumber != "v1" {
+		t.Errorf("Expected L1 hit with v1, got %q", order.TrackNumber)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if order, _ := c.Get("a"); order.TrackNumber != "v2" {
+		t.Errorf("Expected v2 from redis after L1 expiry, got %q", order.TrackNumber)
+	}
+
+	stats := c.GetStats()
+	if stats.RedisDBKeys != 1 || stats.L1 == nil || stats.L1.Count != 1 {
+		t.Errorf("Expected one order on both levels, got %+v", stats)
+	}
+}
+
+func TestRedisCache_DeleteFlushEntries(t *testing.T) {
+	c := newTestRedisCache(t, config.CacheConfig{DefaultTTL: time.Hour, NegativeTTL: time.Hour})
+
+	c.Add(models.Order{OrderUID: "a"})
+	c.Add(models.Order{OrderUID: "b"})
+	c.MarkMissing("c")
+	// Чужой ключ с тем же префиксом
+	if _, err := c.client.do("SET", "test:session", "x"); err != nil {
+		t.Fatalf("Failed to set foreign key: %v", err)
+	}
+
+	entries := c.Entries()
+	if len(entries) != 2 || entries[0].OrderUID != "a" || entries[0].ExpiresAt.IsZero() {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+
+	if !c.Delete("a") {
+		t.Error("Expected a to be deleted")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected deleted order to be a miss")
+	}
+
+	c.Flush()
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected flush to remove orders")
+	}
+	if c.IsMissing("c") {
+		t.Error("Expected flush to remove missing marks")
+	}
+	if n, _ := c.client.do("EXISTS", "test:session"); n != int64(1) {
+		t.Error("Expected flush to keep keys it does not own")
+	}
+}
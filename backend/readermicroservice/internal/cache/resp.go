@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respError — ошибка, которую вернул сервер (ответ вида "-ERR ...")
+type respError string
+
+func (e respError) Error() string { return string(e) }
+
+// respConn — одно соединение с сервером, говорящим на протоколе Redis (RESP2)
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// respClient — минимальный клиент протокола Redis с пулом соединений.
+// Поддерживает только то, что нужно кэшу: команды из строковых аргументов и конвейеры
+type respClient struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	pool     chan *respConn
+}
+
+func newRESPClient(addr, password string, db, poolSize int, timeout time.Duration) *respClient {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	return &respClient{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		pool:     make(chan *respConn, poolSize),
+	}
+}
+
+// dial открывает соединение, авторизуется и выбирает базу
+func (c *respClient) dial() (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.addr, err)
+	}
+	rc := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	var setup [][]string
+	if c.password != "" {
+		setup = append(setup, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	if len(setup) > 0 {
+		replies, err := c.exec(rc, setup)
+		if err == nil {
+			err = firstError(replies)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set up connection: %w", err)
+		}
+	}
+
+	return rc, nil
+}
+
+// do выполняет одну команду
+func (c *respClient) do(args ...string) (interface{}, error) {
+	replies, err := c.pipeline([][]string{args})
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := replies[0].(respError); ok {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// pipeline отправляет команды одним пакетом и читает ответы по порядку.
+// Ошибки сервера возвращаются в ответах как respError, ошибка соединения — вторым значением
+func (c *respClient) pipeline(cmds [][]string) ([]interface{}, error) {
+	var rc *respConn
+	select {
+	case rc = <-c.pool:
+	default:
+		var err error
+		if rc, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	replies, err := c.exec(rc, cmds)
+	if err != nil {
+		// Состояние соединения после ошибки ввода-вывода неизвестно
+		rc.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.pool <- rc:
+	default:
+		rc.conn.Close()
+	}
+	return replies, nil
+}
+
+func (c *respClient) exec(rc *respConn, cmds [][]string) ([]interface{}, error) {
+	if c.timeout > 0 {
+		if err := rc.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, args := range cmds {
+		if err := writeCommand(rc.w, args); err != nil {
+			return nil, fmt.Errorf("failed to write command: %w", err)
+		}
+	}
+	if err := rc.w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to send commands: %w", err)
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(rc.r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reply: %w", err)
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// Close закрывает все свободные соединения пула
+func (c *respClient) Close() {
+	for {
+		select {
+		case rc := <-c.pool:
+			rc.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// writeCommand записывает команду как массив bulk-строк
+func writeCommand(w *bufio.Writer, args []string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readReply читает один ответ: string (простая строка), respError, int64,
+// []byte (bulk-строка), nil (пустой ответ) или []interface{} (массив)
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// firstError возвращает первую ошибку сервера среди ответов конвейера
+func firstError(replies []interface{}) error {
+	for _, reply := range replies {
+		if err, ok := reply.(respError); ok {
+			return err
+		}
+	}
+	return nil
+}
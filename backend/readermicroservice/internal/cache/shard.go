@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"readermicroservice/internal/models"
+)
+
+// entry — элемент кэша. Поля доступа меняются атомарно, чтобы попадание в кэш
+// обходилось блокировкой шарда на чтение
+type entry struct {
+	order      models.Order
+	createdAt  time.Time
+	size       int64         // оценка занимаемой памяти, см. estimateSize
+	ttl        time.Duration // 0 — элемент не истекает
+	expiresAt  atomic.Int64  // unix-время истечения в наносекундах, 0 — не истекает
+	lastAccess atomic.Int64  // unix-время последнего доступа в наносекундах
+	referenced atomic.Bool   // был ли доступ с момента последнего прохода вытеснения
+	frequency  atomic.Uint32 // счетчик обращений для LFU
+	inMain     bool          // элемент в основном сегменте W-TinyLFU, а не в окне
+	element    *list.Element // элемент списка политики вытеснения
+}
+
+func newEntry(order models.Order, size int64, ttl time.Duration, now time.Time) *entry {
+	e := &entry{order: order, size: size, createdAt: now, ttl: ttl}
+	e.lastAccess.Store(now.UnixNano())
+	e.touch(now)
+	return e
+}
+
+// touch продлевает срок жизни элемента на ttl от now
+func (e *entry) touch(now time.Time) {
+	if e.ttl > 0 {
+		e.expiresAt.Store(now.Add(e.ttl).UnixNano())
+	} else {
+		e.expiresAt.Store(0)
+	}
+}
+
+// expired сообщает, истек ли срок жизни элемента к моменту now
+func (e *entry) expired(now time.Time) bool {
+	exp := e.expiresAt.Load()
+	return exp != 0 && now.UnixNano() >= exp
+}
+
+// shard — независимо блокируемый сегмент кэша. Какой элемент вытеснять, решает
+// политика вытеснения (см. evictionPolicy); попадание в кэш только отмечается в
+// атомарных полях элемента. Размер шарда ограничен количеством элементов (maxSize)
+// и/или оценкой занимаемой памяти (maxBytes); 0 — без ограничения
+type shard struct {
+	mu        sync.RWMutex
+	elements  map[string]*entry
+	policy    evictionPolicy
+	maxSize   int
+	maxBytes  int64
+	usedBytes int64
+	counters
+}
+
+func newShard(maxSize int, maxBytes int64, policy string) *shard {
+	return &shard{
+		elements: make(map[string]*entry),
+		policy:   newPolicy(policy, maxSize),
+		maxSize:  maxSize,
+		maxBytes: maxBytes,
+	}
+}
+
+// fits сообщает, поместится ли еще один элемент размером size без вытеснения
+func (s *shard) fits(size int64) bool {
+	if s.maxSize > 0 && len(s.elements) >= s.maxSize {
+		return false
+	}
+	return s.maxBytes <= 0 || s.usedBytes+size <= s.maxBytes
+}
+
+// add добавляет или обновляет заказ со сроком жизни ttl, вытесняя элементы,
+// пока заказ не поместится. Если replace == false, действующий элемент с тем же
+// ключом не перезаписывается. Заказ больше всего бюджета шарда не кэшируется.
+// Возвращает true, если заказ записан в кэш
+func (s *shard) add(order models.Order, ttl time.Duration, now time.Time, replace bool) bool {
+	size := estimateSize(order)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Старая версия заказа освобождает свое место перед вставкой новой
+	overwrite := false
+	if e, exists := s.elements[order.OrderUID]; exists {
+		expired := e.expired(now)
+		if !replace && !expired {
+			return false
+		}
+		s.remove(e)
+		if expired {
+			s.expiredEvictions.Add(1)
+		} else {
+			overwrite = true
+		}
+	}
+
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return false
+	}
+	for !s.fits(size) && len(s.elements) > 0 {
+		s.evict()
+	}
+
+	if overwrite {
+		s.overwrites.Add(1)
+	} else {
+		s.inserts.Add(1)
+	}
+
+	e := newEntry(order, size, ttl, now)
+	s.policy.insert(e, true)
+	s.elements[order.OrderUID] = e
+	s.usedBytes += size
+	return true
+}
+
+// get возвращает заказ, удерживая только блокировку на чтение. Истекший элемент
+// не отдается и сразу удаляется. При sliding срок жизни элемента продлевается
+func (s *shard) get(orderUID string, now time.Time, sliding bool) (models.Order, bool) {
+	s.mu.RLock()
+	e, exists := s.elements[orderUID]
+	if !exists {
+		s.mu.RUnlock()
+		s.misses.Add(1)
+		return models.Order{}, false
+	}
+	if e.expired(now) {
+		s.mu.RUnlock()
+		s.misses.Add(1)
+		s.removeIfExpired(orderUID, now)
+		return models.Order{}, false
+	}
+
+	s.hits.Add(1)
+	s.policy.access(e)
+	e.lastAccess.Store(now.UnixNano())
+	if sliding {
+		e.touch(now)
+	}
+	order := e.order
+	s.mu.RUnlock()
+
+	return order, true
+}
+
+// contains сообщает, есть ли в шарде действующий элемент, не отмечая доступ к нему
+func (s *shard) contains(orderUID string, now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, exists := s.elements[orderUID]
+	return exists && !e.expired(now)
+}
+
+// removeIfExpired удаляет элемент, если он все еще в шарде и его срок жизни истек
+func (s *shard) removeIfExpired(orderUID string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, exists := s.elements[orderUID]; exists && e.expired(now) {
+		s.remove(e)
+		s.expiredEvictions.Add(1)
+	}
+}
+
+// evict вытесняет один элемент, выбранный политикой вытеснения. Вызывается под s.mu
+func (s *shard) evict() {
+	if e := s.policy.victim(); e != nil {
+		s.remove(e)
+		s.capacityEvictions.Add(1)
+	}
+}
+
+// remove удаляет элемент из политики вытеснения и из map. Вызывается под s.mu
+func (s *shard) remove(e *entry) {
+	s.policy.remove(e)
+	delete(s.elements, e.order.OrderUID)
+	s.usedBytes -= e.size
+}
+
+// removeExpired удаляет элементы с истекшим сроком жизни
+func (s *shard) removeExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.elements {
+		if e.expired(now) {
+			s.remove(e)
+			s.expiredEvictions.Add(1)
+		}
+	}
+}
+
+// delete удаляет элемент по ключу и сообщает, был ли он в шарде
+func (s *shard) delete(orderUID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.elements[orderUID]
+	if exists {
+		s.remove(e)
+	}
+	return exists
+}
+
+// reset удаляет все элементы
+func (s *shard) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.elements = make(map[string]*entry)
+	s.policy.reset()
+	s.usedBytes = 0
+}
+
+// fill добавляет заказ при прогреве как наименее ценный, если для него есть место и
+// заказ еще не закэширован. Возвращает false, если заказ не поместился
+func (s *shard) fill(order models.Order, ttl time.Duration, now time.Time) bool {
+	size := estimateSize(order)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.elements[order.OrderUID]; exists {
+		return true
+	}
+	if !s.fits(size) {
+		return false
+	}
+
+	e := newEntry(order, size, ttl, now)
+	s.policy.insert(e, false)
+	s.elements[order.OrderUID] = e
+	s.usedBytes += size
+	s.inserts.Add(1)
+	return true
+}
+
+// appendSnapshot добавляет к entries действующие элементы шарда в порядке политики
+// вытеснения, от наиболее ценных к наименее ценным
+func (s *shard) appendSnapshot(entries []snapshotEntry, now time.Time) []snapshotEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.policy.walk(func(e *entry) {
+		if e.expired(now) {
+			return
+		}
+		entries = append(entries, snapshotEntry{
+			Order:      e.order,
+			CreatedAt:  e.createdAt,
+			TTL:        e.ttl,
+			ExpiresAt:  e.expiresAt.Load(),
+			LastAccess: e.lastAccess.Load(),
+		})
+	})
+	return entries
+}
+
+// restore добавляет элемент из снимка как наименее ценный, сохраняя его срок жизни.
+// Возвращает false, если элемент истек или не поместился
+func (s *shard) restore(se snapshotEntry, now time.Time) bool {
+	size := estimateSize(se.Order)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.elements[se.Order.OrderUID]; exists || !s.fits(size) {
+		return false
+	}
+
+	e := &entry{order: se.Order, size: size, createdAt: se.CreatedAt, ttl: se.TTL}
+	e.expiresAt.Store(se.ExpiresAt)
+	e.lastAccess.Store(se.LastAccess)
+	if e.expired(now) {
+		return false
+	}
+	s.policy.insert(e, false)
+	s.elements[se.Order.OrderUID] = e
+	s.usedBytes += size
+	s.inserts.Add(1)
+	return true
+}
+
+// appendEntries добавляет к entries сведения о действующих элементах шарда
+func (s *shard) appendEntries(entries []EntryInfo, now time.Time) []EntryInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for uid, e := range s.elements {
+		if e.expired(now) {
+			continue
+		}
+		info := EntryInfo{
+			OrderUID:   uid,
+			CreatedAt:  e.createdAt,
+			Age:        now.Sub(e.createdAt),
+			LastAccess: time.Unix(0, e.lastAccess.Load()),
+			Size:       e.size,
+		}
+		if exp := e.expiresAt.Load(); exp != 0 {
+			info.ExpiresAt = time.Unix(0, exp)
+		}
+		entries = append(entries, info)
+	}
+	return entries
+}
+
+// collectStats добавляет к st данные шарда
+func (s *shard) collectStats(st *Stats) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st.Count += len(s.elements)
+	st.UsedBytes += s.usedBytes
+	s.counters.addTo(st)
+	for k := range s.elements {
+		st.Keys = append(st.Keys, k)
+	}
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"container/list"
+	"unsafe"
+
+	"readermicroservice/internal/models"
+)
+
+var (
+	itemStructSize        = int64(unsafe.Sizeof(models.Item{}))
+	discrepancyStructSize = int64(unsafe.Sizeof(models.Discrepancy{}))
+
+	// entryOverhead — память на один заказ без учета строк и слайсов: entry
+	// (включая саму структуру models.Order), элемент списка и примерная
+	// стоимость слота map с ключом
+	entryOverhead = int64(unsafe.Sizeof(entry{})+unsafe.Sizeof(list.Element{})) + 64
+)
+
+// estimateSize приблизительно оценивает, сколько байт памяти займет заказ в кэше:
+// размер структур плюс содержимое всех строк (ключ map разделяет память с OrderUID)
+func estimateSize(o models.Order) int64 {
+	size := entryOverhead
+	size += int64(len(o.OrderUID) + len(o.TrackNumber) + len(o.Entry) + len(o.Locale) +
+		len(o.InternalSignature) + len(o.CustomerID) + len(o.DeliveryService) +
+		len(o.Shardkey) + len(o.OofShard))
+
+	d := o.Delivery
+	size += int64(len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) +
+		len(d.Address) + len(d.Region) + len(d.Email))
+
+	p := o.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) +
+		len(p.Provider) + len(p.Bank))
+
+	size += int64(cap(o.Items)) * itemStructSize
+	for _, it := range o.Items {
+		size += int64(len(it.TrackNumber) + len(it.Rid) + len(it.Name) +
+			len(it.Size) + len(it.Brand))
+	}
+
+	size += int64(cap(o.Discrepancies)) * discrepancyStructSize
+	for _, ds := range o.Discrepancies {
+		size += int64(len(ds.Field))
+	}
+
+	return size
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"sync/atomic"
+)
+
+const (
+	sketchDepth       = 4  // количество строк count-min sketch
+	sketchMaxCount    = 15 // предел счетчика
+	sketchMinWidth    = 64
+	sketchDefaultSize = 1024 // ожидаемое количество элементов, если емкость шарда не ограничена
+	sketchSampleRatio = 10   // после sampleRatio*width обращений счетчики делятся пополам
+)
+
+// frequencySketch — count-min sketch с насыщающимися счетчиками для оценки частоты
+// обращений к ключам, в том числе уже вытесненным. Счетчики атомарные, поэтому
+// increment вызывается под блокировкой шарда на чтение. Периодическое деление
+// счетчиков пополам старит частоты, чтобы sketch следил за изменением популярности
+type frequencySketch struct {
+	counters   []atomic.Uint32
+	width      uint64
+	additions  atomic.Int64
+	sampleSize int64
+}
+
+func newFrequencySketch(capacity int) *frequencySketch {
+	if capacity <= 0 {
+		capacity = sketchDefaultSize
+	}
+	width := uint64(sketchMinWidth)
+	for width < uint64(capacity) {
+		width <<= 1
+	}
+	return &frequencySketch{
+		counters:   make([]atomic.Uint32, sketchDepth*width),
+		width:      width,
+		sampleSize: sketchSampleRatio * int64(width),
+	}
+}
+
+// indexes возвращает позиции счетчиков ключа во всех строках (двойное хеширование FNV-1a)
+func (s *frequencySketch) indexes(key string) [sketchDepth]uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime64
+	}
+	h1, h2 := h, (h>>32)|1
+
+	var idx [sketchDepth]uint64
+	for i := range idx {
+		idx[i] = uint64(i)*s.width + (h1+uint64(i)*h2)&(s.width-1)
+	}
+	return idx
+}
+
+// increment отмечает обращение к ключу
+func (s *frequencySketch) increment(key string) {
+	for _, i := range s.indexes(key) {
+		c := &s.counters[i]
+		for {
+			v := c.Load()
+			if v >= sketchMaxCount || c.CompareAndSwap(v, v+1) {
+				break
+			}
+		}
+	}
+
+	if n := s.additions.Add(1); n >= s.sampleSize && s.additions.CompareAndSwap(n, 0) {
+		s.age()
+	}
+}
+
+// estimate возвращает оценку частоты обращений к ключу
+func (s *frequencySketch) estimate(key string) uint32 {
+	est := uint32(sketchMaxCount)
+	for _, i := range s.indexes(key) {
+		est = min(est, s.counters[i].Load())
+	}
+	return est
+}
+
+// age делит все счетчики пополам
+func (s *frequencySketch) age() {
+	for i := range s.counters {
+		c := &s.counters[i]
+		for {
+			v := c.Load()
+			if c.CompareAndSwap(v, v/2) {
+				break
+			}
+		}
+	}
+}
+
+func (s *frequencySketch) reset() {
+	for i := range s.counters {
+		s.counters[i].Store(0)
+	}
+	s.additions.Store(0)
+}
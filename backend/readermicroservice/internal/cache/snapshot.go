@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+// Формат файла снимка: snapshotMagic, версия формата (uint16, big endian), затем
+// snapshotFile в кодировке gob. При несовместимом изменении snapshotEntry или
+// models.Order версию нужно увеличить, тогда старые снимки будут проигнорированы
+const (
+	snapshotMagic   = "WBL0CACHE"
+	snapshotVersion = uint16(1)
+)
+
+var (
+	ErrSnapshotVersion = errors.New("unsupported cache snapshot version")
+	ErrSnapshotStale   = errors.New("cache snapshot is too old")
+)
+
+// Snapshotter — кэш, который умеет сохранять свое содержимое на диск и
+// восстанавливать его. Реализован только кэшем в памяти процесса
+type Snapshotter interface {
+	SaveSnapshot(path string) error
+	LoadSnapshot(path string, maxAge time.Duration) (int, error)
+}
+
+type snapshotFile struct {
+	CreatedAt time.Time
+	Entries   []snapshotEntry
+}
+
+// snapshotEntry — элемент кэша в снимке. Срок жизни сохраняется абсолютным
+// временем, поэтому время простоя сервиса засчитывается в срок жизни заказа
+type snapshotEntry struct {
+	Order      models.Order
+	CreatedAt  time.Time
+	TTL        time.Duration
+	ExpiresAt  int64
+	LastAccess int64
+}
+
+// SaveSnapshot атомарно записывает содержимое кэша в файл: снимок пишется во
+// временный файл рядом с path и переименовывается после успешной записи
+func (c *Cache) SaveSnapshot(path string) error {
+	snap := snapshotFile{CreatedAt: time.Now()}
+	for _, s := range c.shards {
+		snap.Entries = s.appendSnapshot(snap.Entries, snap.CreatedAt)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	if err := writeSnapshot(w, &snap); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot заменяет содержимое кэша заказами из снимка и возвращает их количество.
+// Снимок старше maxAge (если maxAge > 0) не загружается. Истекшие заказы и заказы,
+// не поместившиеся в текущие ограничения кэша, пропускаются. При любой ошибке
+// содержимое кэша не меняется
+func (c *Cache) LoadSnapshot(path string, maxAge time.Duration) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	snap, err := readSnapshot(bufio.NewReader(f))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if maxAge > 0 && now.Sub(snap.CreatedAt) > maxAge {
+		return 0, fmt.Errorf("%w: created at %s", ErrSnapshotStale, snap.CreatedAt.Format(time.RFC3339))
+	}
+
+	c.Flush()
+
+	loaded := 0
+	for _, se := range snap.Entries {
+		if c.shardFor(se.Order.OrderUID).restore(se, now) {
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+func writeSnapshot(w io.Writer, snap *snapshotFile) error {
+	if _, err := io.WriteString(w, snapshotMagic); err != nil {
+		return fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, snapshotVersion); err != nil {
+		return fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+	if err := gob.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return nil
+}
+
+func readSnapshot(r io.Reader) (*snapshotFile, error) {
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, errors.New("not a cache snapshot")
+	}
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot version: %w", err)
+	}
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+
+	var snap snapshotFile
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// Restore заполняет кэш при запуске: из снимка, если он настроен и пригоден,
+// иначе из БД через ResetDB
+func Restore(c OrderCache, db OrderDatabase, conf *config.CacheConfig) {
+	s, ok := c.(Snapshotter)
+	if !ok || conf.Snapshot.Path == "" {
+		c.ResetDB(db)
+		return
+	}
+
+	n, err := s.LoadSnapshot(conf.Snapshot.Path, conf.Snapshot.MaxAge)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			config.RLogger.Printf("Cache snapshot %s not found, loading cache from DB", conf.Snapshot.Path)
+		} else {
+			config.RLogger.Printf("Error loading cache snapshot, loading cache from DB: %v", err)
+		}
+		c.ResetDB(db)
+		return
+	}
+	config.RLogger.Printf("Cache restored from snapshot with %d orders", n)
+}
+
+// WriteSnapshot сохраняет снимок кэша, если кэш это поддерживает и путь задан
+func WriteSnapshot(c OrderCache, conf *config.CacheConfig) {
+	s, ok := c.(Snapshotter)
+	if !ok || conf.Snapshot.Path == "" {
+		return
+	}
+	if err := s.SaveSnapshot(conf.Snapshot.Path); err != nil {
+		config.RLogger.Printf("Error saving cache snapshot: %v", err)
+	}
+}
+
+// RunSnapshots периодически сохраняет снимок кэша, пока не отменен ctx
+func RunSnapshots(ctx context.Context, c OrderCache, conf *config.CacheConfig) {
+	if _, ok := c.(Snapshotter); !ok || conf.Snapshot.Path == "" || conf.Snapshot.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(conf.Snapshot.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			WriteSnapshot(c, conf)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+type stubDB struct {
+	orders []models.Order
+	calls  int
+}
+
+func (d *stubDB) IterateRecent(ctx context.Context, limit int, fn func(models.Order) bool) error {
+	d.calls++
+	for _, o := range d.orders {
+		if !fn(o) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestCache_SnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := newTestCache(t, 10, 2)
+	src.Add(models.Order{OrderUID: "a", TrackNumber: "track", Items: []models.Item{{ChrtID: 1}}})
+	src.AddWithTTL(models.Order{OrderUID: "short"}, 20*time.Millisecond)
+	src.AddWithTTL(models.Order{OrderUID: "b"}, time.Hour)
+	if err := src.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	dst := newTestCache(t, 10, 2)
+	n, err := dst.LoadSnapshot(path, time.Hour)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 orders restored without the expired one, got %d", n)
+	}
+	order, ok := dst.Get("a")
+	if !ok || order.TrackNumber != "track" || len(order.Items) != 1 {
+		t.Errorf("Expected order a to be restored, got %+v", order)
+	}
+	if _, ok := dst.Get("short"); ok {
+		t.Error("Expected order expired during downtime not to be restored")
+	}
+}
+
+func TestCache_SnapshotStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	src := newTestCache(t, 10, 1)
+	src.Add(models.Order{OrderUID: "a"})
+	if err := src.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	dst := newTestCache(t, 10, 1)
+	if _, err := dst.LoadSnapshot(path, 10*time.Millisecond); !errors.Is(err, ErrSnapshotStale) {
+		t.Errorf("Expected ErrSnapshotStale, got %v", err)
+	}
+}
+
+func TestRestore_FallsBackToDB(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.snapshot")
+	if err := os.WriteFile(corrupt, []byte(snapshotMagic+"\x00\x01garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wrongVersion := filepath.Join(dir, "v0.snapshot")
+	if err := os.WriteFile(wrongVersion, []byte(snapshotMagic+"\x00\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"missing":       filepath.Join(dir, "missing.snapshot"),
+		"corrupt":       corrupt,
+		"wrong version": wrongVersion,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newTestCache(t, 10, 1)
+			db := &stubDB{orders: []models.Order{{OrderUID: "from-db"}}}
+
+			Restore(c, db, &config.CacheConfig{Snapshot: config.SnapshotConfig{Path: path}})
+
+			if db.calls != 1 {
+				t.Errorf("Expected cache to be loaded from DB, got %d calls", db.calls)
+			}
+			if _, ok := c.Get("from-db"); !ok {
+				t.Error("Expected order from DB to be cached")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats — снимок состояния кэша. Счетчики накапливаются с момента запуска сервиса
+type Stats struct {
+	Count             int           `json:"count"`                   // заказов в кэше; для Redis не считается
+	RedisDBKeys       int64         `json:"redis_db_keys,omitempty"` // DBSIZE базы Redis: заказы, отметки об отсутствии и чужие ключи
+	MaxSize           int           `json:"max_size"`
+	UsedBytes         int64         `json:"used_bytes"`
+	MaxBytes          int64         `json:"max_bytes"`
+	Hits              int64         `json:"hits"`
+	Misses            int64         `json:"misses"`
+	HitRatio          float64       `json:"hit_ratio"`
+	Inserts           int64         `json:"inserts"`
+	Overwrites        int64         `json:"overwrites"`
+	CapacityEvictions int64         `json:"capacity_evictions"`
+	ExpiredEvictions  int64         `json:"expired_evictions"`
+	AvgGetLatency     time.Duration `json:"avg_get_latency_ns"`
+	Keys              []string      `json:"-"`
+	L1                *Stats        `json:"l1,omitempty"` // локальный кэш первого уровня перед Redis
+
+	getTime time.Duration // суммарное время Get, из него считается AvgGetLatency
+}
+
+// EntryInfo — сведения о закэшированном заказе для администрирования
+type EntryInfo struct {
+	OrderUID   string        `json:"order_uid"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Age        time.Duration `json:"age_ns"`
+	LastAccess time.Time     `json:"last_access,omitzero"` // не известно для Redis
+	ExpiresAt  time.Time     `json:"expires_at,omitzero"`  // пусто, если заказ не истекает
+	Size       int64         `json:"size_bytes,omitempty"`
+}
+
+// counters — счетчики статистики. Меняются атомарно, поэтому попадания
+// учитываются под блокировкой шарда на чтение
+type counters struct {
+	hits              atomic.Int64
+	misses            atomic.Int64
+	inserts           atomic.Int64
+	overwrites        atomic.Int64
+	capacityEvictions atomic.Int64
+	expiredEvictions  atomic.Int64
+	getTime           atomic.Int64 // наносекунды
+}
+
+// addTo добавляет значения счетчиков к st
+func (c *counters) addTo(st *Stats) {
+	st.Hits += c.hits.Load()
+	st.Misses += c.misses.Load()
+	st.Inserts += c.inserts.Load()
+	st.Overwrites += c.overwrites.Load()
+	st.CapacityEvictions += c.capacityEvictions.Load()
+	st.ExpiredEvictions += c.expiredEvictions.Load()
+	st.getTime += time.Duration(c.getTime.Load())
+}
+
+// derive вычисляет производные показатели после сложения счетчиков
+func (st *Stats) derive() {
+	if lookups := st.Hits + st.Misses; lookups > 0 {
+		st.HitRatio = float64(st.Hits) / float64(lookups)
+		st.AvgGetLatency = st.getTime / time.Duration(lookups)
+	}
+}
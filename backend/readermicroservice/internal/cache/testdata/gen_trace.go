@@ -0,0 +1,90 @@
+//go:build ignore
+
+// gen_trace генерирует синтетическую трассу обращений access_trace.txt.gz для
+// тестов и бенчмарков политик вытеснения. Трасса не записана с работающего сервиса,
+// а моделирует его профиль нагрузки:
+//   - обращения к 20000 заказам по закону Ципфа с показателем 0.9 (популярность
+//     не связана с номером заказа);
+//   - через каждые 8000 обращений — последовательный проход по 1500 ранее не
+//     запрашивавшимся заказам, как при выгрузке из инструментов поддержки.
+//
+// Генератор детерминирован (фиксированное зерно), поэтому трасса и доли попаданий
+// в BenchmarkEviction_HitRatio воспроизводимы. Запуск из internal/cache:
+//
+//	go generate
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+)
+
+const (
+	seed         = 42
+	universe     = 20000 // различных «обычных» заказов
+	zipfExponent = 0.9
+	minLength    = 80000 // обращений в трассе, не считая хвоста последней пачки
+	batch        = 500   // обращений по закону Ципфа между проверками на проход
+	scanEvery    = 8000
+	scanLength   = 1500
+	output       = "testdata/access_trace.txt.gz"
+)
+
+func main() {
+	rng := rand.New(rand.NewPCG(seed, 0))
+
+	// Популярность по рангу; ранги случайно сопоставлены номерам заказов
+	ids := rng.Perm(universe)
+	cumulative := make([]float64, universe)
+	sum := 0.0
+	for i := range cumulative {
+		sum += 1 / math.Pow(float64(i+1), zipfExponent)
+		cumulative[i] = sum
+	}
+	pick := func() int {
+		return ids[sort.SearchFloat64s(cumulative, rng.Float64()*sum)]
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	w := bufio.NewWriter(gz)
+
+	fmt.Fprintln(w, "# order_uid access trace for eviction policy benchmarks: Zipf-skewed lookups")
+	fmt.Fprintln(w, "# with periodic sequential scans over rarely requested orders")
+	fmt.Fprintln(w, "# generated by testdata/gen_trace.go, do not edit")
+
+	total, scanNext := 0, 100000
+	for total < minLength {
+		if total > 0 && total%scanEvery == 0 {
+			for k := 0; k < scanLength; k++ {
+				fmt.Fprintf(w, "s%06d\n", scanNext)
+				scanNext++
+				total++
+			}
+		}
+		for k := 0; k < batch; k++ {
+			fmt.Fprintf(w, "o%05d\n", pick())
+			total++
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d accesses to %s", total, output)
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"time"
+
+	"readermicroservice/internal/models"
+)
+
+// Tiered — двухуровневый кэш: небольшой кэш в памяти процесса (L1) перед общим
+// кэшем в Redis (L2). Заказы живут в L1 не дольше l1TTL, поэтому изменения,
+// сделанные другими репликами, становятся видны не позже чем через l1TTL
+type Tiered struct {
+	l1    *Cache
+	l2    *RedisCache
+	l1TTL time.Duration
+}
+
+// NewTiered создает двухуровневый кэш
+func NewTiered(l1 *Cache, l2 *RedisCache, l1TTL time.Duration) *Tiered {
+	return &Tiered{l1: l1, l2: l2, l1TTL: l1TTL}
+}
+
+// Add записывает заказ в Redis, затем в L1
+func (t *Tiered) Add(order models.Order) {
+	t.l2.Add(order)
+	t.l1.AddWithTTL(order, t.l1TTL)
+}
+
+// AddWithTTL записывает заказ в Redis со сроком жизни ttl, в L1 — не дольше l1TTL
+func (t *Tiered) AddWithTTL(order models.Order, ttl time.Duration) {
+	t.l2.AddWithTTL(order, ttl)
+	t.l1.AddWithTTL(order, t.localTTL(ttl))
+}
+
+// AddIfAbsent добавляет заказ, если его нет в Redis. В L1 заказ попадает,
+// только если был записан в Redis, чтобы не закрепить локально устаревшую версию
+func (t *Tiered) AddIfAbsent(order models.Order) bool {
+	if !t.l2.AddIfAbsent(order) {
+		return false
+	}
+	t.l1.AddWithTTL(order, t.l1TTL)
+	return true
+}
+
+// Get ищет заказ сначала в L1, затем в Redis; найденный в Redis заказ попадает в L1
+func (t *Tiered) Get(orderUID string) (models.Order, bool) {
+	if order, ok := t.l1.Get(orderUID); ok {
+		return order, true
+	}
+	order, ok := t.l2.Get(orderUID)
+	if ok {
+		t.l1.AddWithTTL(order, t.l1TTL)
+	}
+	return order, ok
+}
+
+// MarkMissing ставит отметку об отсутствии заказа на обоих уровнях
+func (t *Tiered) MarkMissing(orderUID string) {
+	t.l1.MarkMissing(orderUID)
+	t.l2.MarkMissing(orderUID)
+}
+
+// IsMissing сообщает, известно ли хотя бы одному уровню, что заказа нет в БД
+func (t *Tiered) IsMissing(orderUID string) bool {
+	return t.l1.IsMissing(orderUID) || t.l2.IsMissing(orderUID)
+}
+
+// GetMany ищет заказы в L1, а оставшиеся — одним запросом к Redis
+func (t *Tiered) GetMany(orderUIDs []string) (map[string]models.Order, map[string]bool) {
+	orders, missing := t.l1.GetMany(orderUIDs)
+
+	rest := make([]string, 0, len(orderUIDs)-len(orders))
+	for _, uid := range orderUIDs {
+		if _, ok := orders[uid]; !ok && !missing[uid] {
+			rest = append(rest, uid)
+		}
+	}
+	if len(rest) == 0 {
+		return orders, missing
+	}
+
+	l2Orders, l2Missing := t.l2.GetMany(rest)
+	for uid, order := range l2Orders {
+		orders[uid] = order
+		t.l1.AddWithTTL(order, t.l1TTL)
+	}
+	for uid := range l2Missing {
+		missing[uid] = true
+	}
+	return orders, missing
+}
+
+// AddManyIfAbsent добавляет заказы в Redis; в L1 попадают только записанные в Redis
+func (t *Tiered) AddManyIfAbsent(orders []models.Order) {
+	for _, order := range t.l2.addManyIfAbsent(orders) {
+		t.l1.AddWithTTL(order, t.l1TTL)
+	}
+}
+
+// MarkManyMissing ставит отметки об отсутствии заказов на обоих уровнях
+func (t *Tiered) MarkManyMissing(orderUIDs []string) {
+	t.l1.MarkManyMissing(orderUIDs)
+	t.l2.MarkManyMissing(orderUIDs)
+}
+
+// Delete удаляет заказ на обоих уровнях. Другие реплики могут отдавать заказ
+// из своего L1 еще не дольше l1TTL
+func (t *Tiered) Delete(orderUID string) bool {
+	inL1 := t.l1.Delete(orderUID)
+	return t.l2.Delete(orderUID) || inL1
+}
+
+// Flush очищает оба уровня
+func (t *Tiered) Flush() {
+	t.l2.Flush()
+	t.l1.Flush()
+}
+
+// Entries возвращает сведения о заказах в Redis
+func (t *Tiered) Entries() []EntryInfo {
+	return t.l2.Entries()
+}
+
+// GetStats возвращает статистику Redis со вложенной статистикой L1
+func (t *Tiered) GetStats() Stats {
+	st := t.l2.GetStats()
+	l1 := t.l1.GetStats()
+	st.L1 = &l1
+	return st
+}
+
+// StopCleanup останавливает очистку L1 и закрывает соединения с Redis
+func (t *Tiered) StopCleanup() {
+	t.l1.StopCleanup()
+	t.l2.StopCleanup()
+}
+
+// ResetDB прогревает Redis из БД. L1 только очищается: он заполняется при чтении
+func (t *Tiered) ResetDB(db OrderDatabase) {
+	t.l2.ResetDB(db)
+	t.l1.Flush()
+}
+
+// localTTL ограничивает срок жизни заказа в L1 величиной l1TTL
+func (t *Tiered) localTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > t.l1TTL {
+		return t.l1TTL
+	}
+	return ttl
+}
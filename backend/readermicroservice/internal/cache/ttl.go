@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+// ttlPolicy выбирает срок жизни заказа в кэше
+type ttlPolicy struct {
+	defaultTTL     time.Duration
+	finalStatusTTL time.Duration
+	finalStatuses  map[int]struct{}
+}
+
+func newTTLPolicy(conf *config.CacheConfig) ttlPolicy {
+	p := ttlPolicy{
+		defaultTTL:     conf.DefaultTTL,
+		finalStatusTTL: conf.FinalStatusTTL,
+		finalStatuses:  make(map[int]struct{}, len(conf.FinalStatuses)),
+	}
+	for _, status := range conf.FinalStatuses {
+		p.finalStatuses[status] = struct{}{}
+	}
+	return p
+}
+
+// ttlFor возвращает срок жизни заказа: finalStatusTTL, если все его товары
+// в финальном статусе, иначе defaultTTL
+func (p ttlPolicy) ttlFor(order models.Order) time.Duration {
+	if p.finalStatusTTL <= 0 || len(p.finalStatuses) == 0 || len(order.Items) == 0 {
+		return p.defaultTTL
+	}
+	for _, it := range order.Items {
+		if _, final := p.finalStatuses[it.Status]; !final {
+			return p.defaultTTL
+		}
+	}
+	return p.finalStatusTTL
+}
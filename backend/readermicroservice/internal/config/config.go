@@ -19,14 +19,56 @@ type DBConfig struct {
 }
 
 type KafkaConfig struct {
-	Brokers []string `yaml:"brokers" env:"KAFKA_BROKERS"`
-	Topic   string   `yaml:"topic" env:"KAFKA_TOPIC"`
+	Brokers  []string `yaml:"brokers" env:"KAFKA_BROKERS"`
+	Topic    string   `yaml:"topic" env:"KAFKA_TOPIC"`
+	DLQTopic string   `yaml:"dlq_topic" env:"KAFKA_DLQ_TOPIC"`
+}
+
+// Реализации кэша заказов
+const (
+	CacheBackendMemory = "memory" // кэш в памяти процесса
+	CacheBackendRedis  = "redis"  // общий кэш в Redis-совместимом хранилище
+)
+
+// Политики вытеснения кэша в памяти
+const (
+	EvictionLRU     = "lru"     // вытесняется давно не использованный заказ
+	EvictionLFU     = "lfu"     // вытесняется редко используемый заказ
+	EvictionTinyLFU = "tinylfu" // W-TinyLFU: новые заказы вытесняют старые, только если популярнее их
+)
+
+type RedisConfig struct {
+	Addr      string        `yaml:"addr" env:"REDIS_ADDR"`
+	Password  string        `yaml:"password" env:"REDIS_PASSWORD"`
+	DB        int           `yaml:"db" env:"REDIS_DB"`
+	KeyPrefix string        `yaml:"key_prefix" env:"REDIS_KEY_PREFIX"`
+	PoolSize  int           `yaml:"pool_size" env:"REDIS_POOL_SIZE"`
+	Timeout   time.Duration `yaml:"timeout" env:"REDIS_TIMEOUT"`
+	L1        bool          `yaml:"l1" env:"REDIS_L1"`
+	L1TTL     time.Duration `yaml:"l1_ttl" env:"REDIS_L1_TTL"`
+}
+
+type SnapshotConfig struct {
+	Path     string        `yaml:"path" env:"CACHE_SNAPSHOT_PATH"`
+	Interval time.Duration `yaml:"interval" env:"CACHE_SNAPSHOT_INTERVAL"`
+	MaxAge   time.Duration `yaml:"max_age" env:"CACHE_SNAPSHOT_MAX_AGE"`
 }
 
 type CacheConfig struct {
-	MaxSize         int           `yaml:"max_size" env:"CACHE_MAX_SIZE"`
-	DefaultTTL      time.Duration `yaml:"default_ttl" env:"CACHE_DEFAULT_TTL"`
-	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"CACHE_CLEANUP_INTERVAL"`
+	Backend           string         `yaml:"backend" env:"CACHE_BACKEND"`
+	MaxSize           int            `yaml:"max_size" env:"CACHE_MAX_SIZE"`
+	MaxBytes          int64          `yaml:"max_bytes" env:"CACHE_MAX_BYTES"`
+	DefaultTTL        time.Duration  `yaml:"default_ttl" env:"CACHE_DEFAULT_TTL"`
+	CleanupInterval   time.Duration  `yaml:"cleanup_interval" env:"CACHE_CLEANUP_INTERVAL"`
+	Shards            int            `yaml:"shards" env:"CACHE_SHARDS"`
+	Eviction          string         `yaml:"eviction" env:"CACHE_EVICTION"`
+	SlidingExpiration bool           `yaml:"sliding_expiration" env:"CACHE_SLIDING_EXPIRATION"`
+	FinalStatusTTL    time.Duration  `yaml:"final_status_ttl" env:"CACHE_FINAL_STATUS_TTL"`
+	FinalStatuses     []int          `yaml:"final_statuses" env:"CACHE_FINAL_STATUSES"`
+	NegativeTTL       time.Duration  `yaml:"negative_ttl" env:"CACHE_NEGATIVE_TTL"`
+	NegativeMaxSize   int            `yaml:"negative_max_size" env:"CACHE_NEGATIVE_MAX_SIZE"`
+	Redis             RedisConfig    `yaml:"redis"`
+	Snapshot          SnapshotConfig `yaml:"snapshot"`
 }
 
 type RetryConfig struct {
@@ -34,11 +76,28 @@ type RetryConfig struct {
 	BaseDelay  time.Duration `yaml:"base_delay" env:"RETRY_BASE_DELAY"`
 }
 
+// Режимы сверки сумм заказа
+const (
+	ReconcileOff     = "off"     // сверка не выполняется
+	ReconcileLenient = "lenient" // заказ сохраняется, расхождения записываются вместе с ним
+	ReconcileStrict  = "strict"  // заказ с расхождениями отправляется в DLQ
+)
+
+type ReconcileConfig struct {
+	Mode string `yaml:"mode" env:"RECONCILE_MODE"`
+}
+
+type AdminConfig struct {
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
 type AppConfig struct {
-	DB    DBConfig    `yaml:"db"`
-	Kafka KafkaConfig `yaml:"kafka"`
-	Cache CacheConfig `yaml:"cache"`
-	Retry RetryConfig `yaml:"retry"`
+	DB        DBConfig        `yaml:"db"`
+	Kafka     KafkaConfig     `yaml:"kafka"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Retry     RetryConfig     `yaml:"retry"`
+	Reconcile ReconcileConfig `yaml:"reconcile"`
+	Admin     AdminConfig     `yaml:"admin"`
 }
 
 var RLogger *log.Logger
@@ -63,6 +122,10 @@ func LoadConfig(path string) (*AppConfig, error) {
 		return nil, fmt.Errorf("error overriding with env: %w", err)
 	}
 
+	if err := applyDefaults(&config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -86,6 +149,64 @@ func overrideWithEnv(cfg *AppConfig) error {
 	if db := os.Getenv("DB_NAME"); db != "" {
 		cfg.DB.Database = db
 	}
+	if mode := os.Getenv("RECONCILE_MODE"); mode != "" {
+		cfg.Reconcile.Mode = mode
+	}
+	if backend := os.Getenv("CACHE_BACKEND"); backend != "" {
+		cfg.Cache.Backend = backend
+	}
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		cfg.Cache.Redis.Addr = addr
+	}
+	if pass := os.Getenv("REDIS_PASSWORD"); pass != "" {
+		cfg.Cache.Redis.Password = pass
+	}
+	if eviction := os.Getenv("CACHE_EVICTION"); eviction != "" {
+		cfg.Cache.Eviction = eviction
+	}
+	if path := os.Getenv("CACHE_SNAPSHOT_PATH"); path != "" {
+		cfg.Cache.Snapshot.Path = path
+	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		cfg.Admin.Token = token
+	}
+
+	return nil
+}
+
+// applyDefaults заполняет незаданные значения и проверяет допустимые варианты настроек
+func applyDefaults(cfg *AppConfig) error {
+	switch cfg.Reconcile.Mode {
+	case "":
+		cfg.Reconcile.Mode = ReconcileLenient
+	case ReconcileOff, ReconcileLenient, ReconcileStrict:
+	default:
+		return fmt.Errorf("unknown reconcile mode %q", cfg.Reconcile.Mode)
+	}
+
+	switch cfg.Cache.Backend {
+	case "":
+		cfg.Cache.Backend = CacheBackendMemory
+	case CacheBackendMemory:
+	case CacheBackendRedis:
+		if cfg.Cache.Redis.Addr == "" {
+			return fmt.Errorf("cache.redis.addr is required for redis backend")
+		}
+		// Без префикса ключи кэша не отличить от чужих данных в общем Redis
+		if cfg.Cache.Redis.KeyPrefix == "" {
+			return fmt.Errorf("cache.redis.key_prefix is required for redis backend")
+		}
+	default:
+		return fmt.Errorf("unknown cache backend %q", cfg.Cache.Backend)
+	}
+
+	switch cfg.Cache.Eviction {
+	case "":
+		cfg.Cache.Eviction = EvictionLRU
+	case EvictionLRU, EvictionLFU, EvictionTinyLFU:
+	default:
+		return fmt.Errorf("unknown cache eviction policy %q", cfg.Cache.Eviction)
+	}
 
 	return nil
 }
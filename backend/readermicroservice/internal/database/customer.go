@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"readermicroservice/internal/config"
+)
+
+// CustomerSummary — сводка по всем заказам покупателя
+type CustomerSummary struct {
+	OrderCount   int              `json:"order_count"`
+	TotalSpend   map[string]int64 `json:"total_spend"` // сумма payment.amount по валютам
+	FirstOrderAt *time.Time       `json:"first_order_at,omitempty"`
+	LastOrderAt  *time.Time       `json:"last_order_at,omitempty"`
+}
+
+// GetCustomerSummary считает количество заказов покупателя, траты в каждой валюте
+// и даты первого и последнего заказа. Для неизвестного покупателя OrderCount равен 0
+func (db *DB) GetCustomerSummary(ctx context.Context, customerID string) (CustomerSummary, error) {
+	summary := CustomerSummary{TotalSpend: make(map[string]int64)}
+
+	var first, last sql.NullTime
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*), MIN(date_created), MAX(date_created) FROM orders WHERE customer_id = $1",
+		customerID).Scan(&summary.OrderCount, &first, &last)
+	if err != nil {
+		config.RLogger.Println("Error while reading customer summary: ", err)
+		return CustomerSummary{}, fmt.Errorf("failed to query customer orders: %w", err)
+	}
+	if first.Valid {
+		summary.FirstOrderAt = &first.Time
+	}
+	if last.Valid {
+		summary.LastOrderAt = &last.Time
+	}
+	if summary.OrderCount == 0 {
+		return summary, nil
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT p.currency, SUM(p.amount) FROM payments p JOIN orders o ON o.order_uid = p.order_uid "+
+			"WHERE o.customer_id = $1 GROUP BY p.currency", customerID)
+	if err != nil {
+		config.RLogger.Println("Error while reading customer spend: ", err)
+		return CustomerSummary{}, fmt.Errorf("failed to query customer spend: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currency string
+		var total int64
+		if err := rows.Scan(&currency, &total); err != nil {
+			return CustomerSummary{}, fmt.Errorf("failed to scan customer spend: %w", err)
+		}
+		summary.TotalSpend[currency] = total
+	}
+	if err := rows.Err(); err != nil {
+		return CustomerSummary{}, fmt.Errorf("failed to iterate customer spend: %w", err)
+	}
+	return summary, nil
+}
@@ -1,11 +1,31 @@
 package database
 
-import "readermicroservice/internal/models"
+import (
+	"context"
+
+	"readermicroservice/internal/models"
+)
 
 type Database interface {
+	// Insert атомарно сохраняет заказ. Только после успешного возврата
+	// заказ можно добавлять в cache.OrderCache. ErrStaleVersion означает, что
+	// в БД уже есть более новая версия и заказ не сохранен
 	Insert(data models.Order) error
+	// GetByUID возвращает ErrNotFound, если заказа нет
 	GetByUID(orderUID string) (*models.Order, error)
+	// GetByUIDs загружает заказы по списку order_uid одним набором запросов
+	GetByUIDs(ctx context.Context, uids []string) ([]models.Order, error)
+	// FindOrderUIDs ищет заказы по номеру отслеживания, транзакции или rid товара
+	FindOrderUIDs(ctx context.Context, by Lookup, value string) ([]string, error)
+	// GetCustomerSummary возвращает сводку по заказам покупателя
+	GetCustomerSummary(ctx context.Context, customerID string) (CustomerSummary, error)
 	GetAll() ([]models.Order, error)
+	// ListOrders возвращает страницу заказов, подходящих под filter, от новых к старым
+	ListOrders(ctx context.Context, filter OrderFilter, after *Cursor, limit int) (OrderPage, error)
+	// Search ищет заказы по данным получателя и товаров полнотекстовым поиском
+	Search(ctx context.Context, query string, limit, offset int) (SearchPage, error)
+	// IterateRecent обходит не более limit самых новых заказов, пока fn возвращает true
+	IterateRecent(ctx context.Context, limit int, fn func(models.Order) bool) error
 	Close() error
 	Ping() error
 }
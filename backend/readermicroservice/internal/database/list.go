@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+// OrderFilter — условия отбора заказов для ListOrders. Пустые поля не учитываются
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	Locale          string
+	Currency        string    // payment.currency
+	Bank            string    // payment.bank
+	CreatedFrom     time.Time // date_created >= CreatedFrom
+	CreatedTo       time.Time // date_created < CreatedTo
+}
+
+// Cursor — позиция в выдаче ListOrders: ключ последнего заказа предыдущей страницы
+type Cursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// OrderPage — страница заказов. Next равен nil на последней странице
+type OrderPage struct {
+	Orders []models.Order
+	Next   *Cursor
+}
+
+// queryBuilder собирает условие WHERE с пронумерованными параметрами
+type queryBuilder struct {
+	conds []string
+	args  []interface{}
+}
+
+// arg добавляет значение параметра и возвращает его плейсхолдер
+func (b *queryBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *queryBuilder) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+// ListOrders возвращает не более limit заказов, подходящих под filter, от новых к старым
+// (по date_created, затем по order_uid). Страницы выбираются по курсору (keyset),
+// поэтому стоимость запроса не зависит от номера страницы. after == nil — первая страница
+func (db *DB) ListOrders(ctx context.Context, filter OrderFilter, after *Cursor, limit int) (OrderPage, error) {
+	var b queryBuilder
+	b.where("date_created IS NOT NULL")
+
+	if filter.CustomerID != "" {
+		b.where("customer_id = " + b.arg(filter.CustomerID))
+	}
+	if filter.DeliveryService != "" {
+		b.where("delivery_service = " + b.arg(filter.DeliveryService))
+	}
+	if filter.Locale != "" {
+		b.where("locale = " + b.arg(filter.Locale))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		b.where("date_created >= " + b.arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		b.where("date_created < " + b.arg(filter.CreatedTo))
+	}
+	if filter.Currency != "" || filter.Bank != "" {
+		payment := "SELECT 1 FROM payments p WHERE p.order_uid = orders.order_uid"
+		if filter.Currency != "" {
+			payment += " AND p.currency = " + b.arg(filter.Currency)
+		}
+		if filter.Bank != "" {
+			payment += " AND p.bank = " + b.arg(filter.Bank)
+		}
+		b.where("EXISTS (" + payment + ")")
+	}
+	if after != nil {
+		b.where("(date_created, order_uid) < (" + b.arg(after.DateCreated) + ", " + b.arg(after.OrderUID) + ")")
+	}
+
+	// Лишняя строка показывает, есть ли следующая страница
+	query := "SELECT " + orderColumns + " FROM orders WHERE " + strings.Join(b.conds, " AND ") +
+		" ORDER BY date_created DESC, order_uid DESC LIMIT " + b.arg(limit+1)
+
+	orders, err := db.queryOrders(ctx, query, b.args...)
+	if err != nil {
+		config.RLogger.Println("Error while listing orders: ", err)
+		return OrderPage{}, err
+	}
+
+	var page OrderPage
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[len(orders)-1]
+		page.Next = &Cursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}
+	}
+
+	page.Orders, err = db.attachDetailsByUID(ctx, orders)
+	if err != nil {
+		config.RLogger.Println("Error while reading listed order details: ", err)
+		return OrderPage{}, fmt.Errorf("failed to load order details: %w", err)
+	}
+	return page, nil
+}
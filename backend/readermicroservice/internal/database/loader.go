@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	deliverySelect = "SELECT order_uid, name, phone, zip, city, address, region, email FROM delivery"
+	paymentSelect  = "SELECT order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payments"
+	itemSelect     = "SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items"
+)
+
+// queryOrders выбирает строки orders без связанных сущностей
+func (db *DB) queryOrders(ctx context.Context, query string, args ...interface{}) ([]models.Order, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]models.Order, 0)
+	for rows.Next() {
+		var order models.Order
+		if err := scanOrder(rows, &order); err != nil {
+			config.RLogger.Println("Error scanning order: ", err)
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate orders: %w", err)
+	}
+	return orders, nil
+}
+
+// attachDetailsByUID дополняет заказы доставкой, оплатой и товарами, выбирая
+// связанные строки только для переданных заказов
+func (db *DB) attachDetailsByUID(ctx context.Context, orders []models.Order) ([]models.Order, error) {
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	uids := make([]string, len(orders))
+	for i := range orders {
+		uids[i] = orders[i].OrderUID
+	}
+
+	return db.attachDetails(ctx, orders, " WHERE order_uid = ANY($1)", pq.Array(uids))
+}
+
+// attachDetails дополняет заказы доставкой, оплатой и товарами тремя запросами
+// независимо от количества заказов. filter — условие WHERE для связанных таблиц
+// (пустое — все строки). Заказы без доставки или оплаты отбрасываются
+func (db *DB) attachDetails(ctx context.Context, orders []models.Order, filter string, args ...interface{}) ([]models.Order, error) {
+	index := make(map[string]int, len(orders))
+	for i := range orders {
+		index[orders[i].OrderUID] = i
+	}
+	hasDelivery := make([]bool, len(orders))
+	hasPayment := make([]bool, len(orders))
+
+	// Доставка
+	rows, err := db.QueryContext(ctx, deliverySelect+filter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delivery: %w", err)
+	}
+	for rows.Next() {
+		var uid string
+		var d models.Delivery
+		if err := rows.Scan(&uid, &d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email); err != nil {
+			config.RLogger.Println("Error scanning delivery: ", err)
+			continue
+		}
+		if i, ok := index[uid]; ok {
+			orders[i].Delivery = d
+			hasDelivery[i] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate delivery: %w", err)
+	}
+
+	// Оплата
+	rows, err = db.QueryContext(ctx, paymentSelect+filter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments: %w", err)
+	}
+	for rows.Next() {
+		var uid string
+		var p models.Payment
+		if err := rows.Scan(&uid, &p.Transaction, &p.RequestID, &p.Currency, &p.Provider, &p.Amount,
+			&p.PaymentDt, &p.Bank, &p.DeliveryCost, &p.GoodsTotal, &p.CustomFee); err != nil {
+			config.RLogger.Println("Error scanning payment: ", err)
+			continue
+		}
+		if i, ok := index[uid]; ok {
+			orders[i].Payment = p
+			hasPayment[i] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate payments: %w", err)
+	}
+
+	// Товары — в порядке вставки
+	rows, err = db.QueryContext(ctx, itemSelect+filter+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query items: %w", err)
+	}
+	for rows.Next() {
+		var uid string
+		var it models.Item
+		if err := rows.Scan(&uid, &it.ChrtID, &it.TrackNumber, &it.Price, &it.Rid, &it.Name,
+			&it.Sale, &it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status); err != nil {
+			config.RLogger.Println("Error scanning item: ", err)
+			continue
+		}
+		if i, ok := index[uid]; ok {
+			orders[i].Items = append(orders[i].Items, it)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate items: %w", err)
+	}
+
+	complete := orders[:0]
+	for i := range orders {
+		if !hasDelivery[i] || !hasPayment[i] {
+			config.RLogger.Println("Skipping order without delivery or payment: ", orders[i].OrderUID)
+			continue
+		}
+		complete = append(complete, orders[i])
+	}
+	return complete, nil
+}
+
+// recentBatchSize — сколько заказов IterateRecent читает из БД за один запрос
+const recentBatchSize = 500
+
+// IterateRecent обходит не более limit самых новых заказов (по date_created) от новых
+// к старым и вызывает fn для каждого. Заказы читаются пачками по курсору
+// (date_created, order_uid), поэтому в памяти одновременно находится только одна пачка.
+// Обход прекращается, если fn вернула false
+func (db *DB) IterateRecent(ctx context.Context, limit int, fn func(models.Order) bool) error {
+	const base = "SELECT " + orderColumns + " FROM orders WHERE date_created IS NOT NULL"
+	const order = " ORDER BY date_created DESC, order_uid DESC LIMIT $"
+
+	var (
+		cursor  *models.Order
+		fetched int
+	)
+	for fetched < limit {
+		batch := min(recentBatchSize, limit-fetched)
+
+		var (
+			orders []models.Order
+			err    error
+		)
+		if cursor == nil {
+			orders, err = db.queryOrders(ctx, base+order+"1", batch)
+		} else {
+			orders, err = db.queryOrders(ctx, base+" AND (date_created, order_uid) < ($1, $2)"+order+"3",
+				cursor.DateCreated, cursor.OrderUID, batch)
+		}
+		if err != nil {
+			config.RLogger.Println("Error while reading recent orders: ", err)
+			return err
+		}
+		if len(orders) == 0 {
+			return nil
+		}
+		fetched += len(orders)
+		lastPage := len(orders) < batch
+		last := orders[len(orders)-1]
+		cursor = &last
+
+		orders, err = db.attachDetailsByUID(ctx, orders)
+		if err != nil {
+			config.RLogger.Println("Error while reading recent order details: ", err)
+			return err
+		}
+
+		for _, o := range orders {
+			if !fn(o) {
+				return nil
+			}
+		}
+
+		if lastPage {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Lookup — вторичный идентификатор, по которому ищутся заказы
+type Lookup int
+
+const (
+	LookupTrackNumber Lookup = iota // orders.track_number
+	LookupTransaction               // payments.transaction
+	LookupItemRid                   // items.rid
+)
+
+// MaxLookupResults — сколько заказов самое большее возвращает FindOrderUIDs
+const MaxLookupResults = 100
+
+// lookupConditions — условие отбора orders для каждого идентификатора; $1 — значение
+var lookupConditions = map[Lookup]string{
+	LookupTrackNumber: "track_number = $1",
+	LookupTransaction: "EXISTS (SELECT 1 FROM payments p WHERE p.order_uid = orders.order_uid AND p.transaction = $1)",
+	LookupItemRid:     "EXISTS (SELECT 1 FROM items i WHERE i.order_uid = orders.order_uid AND i.rid = $1)",
+}
+
+// FindOrderUIDs возвращает order_uid заказов с указанным идентификатором, от новых
+// к старым, не больше MaxLookupResults. Пустой результат — не ошибка
+func (db *DB) FindOrderUIDs(ctx context.Context, by Lookup, value string) ([]string, error) {
+	cond, ok := lookupConditions[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown lookup %d", by)
+	}
+
+	query := "SELECT order_uid FROM orders WHERE " + cond +
+		" ORDER BY date_created DESC NULLS LAST, order_uid DESC LIMIT $2"
+	rows, err := db.QueryContext(ctx, query, value, MaxLookupResults)
+	if err != nil {
+		config.RLogger.Println("Error while looking up orders: ", err)
+		return nil, fmt.Errorf("failed to look up orders: %w", err)
+	}
+	defer rows.Close()
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, fmt.Errorf("failed to scan order uid: %w", err)
+		}
+		uids = append(uids, uid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate order uids: %w", err)
+	}
+	return uids, nil
+}
+
+// GetByUIDs загружает заказы по списку order_uid четырьмя запросами независимо от
+// длины списка. Заказы возвращаются в порядке uids; отсутствующие в БД пропускаются
+func (db *DB) GetByUIDs(ctx context.Context, uids []string) ([]models.Order, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	orders, err := db.queryOrders(ctx, "SELECT "+orderColumns+" FROM orders WHERE order_uid = ANY($1)", pq.Array(uids))
+	if err != nil {
+		config.RLogger.Println("Error while reading orders by uids: ", err)
+		return nil, err
+	}
+	orders, err = db.attachDetailsByUID(ctx, orders)
+	if err != nil {
+		config.RLogger.Println("Error while reading order details by uids: ", err)
+		return nil, err
+	}
+
+	byUID := make(map[string]models.Order, len(orders))
+	for _, o := range orders {
+		byUID[o.OrderUID] = o
+	}
+	result := make([]models.Order, 0, len(orders))
+	for _, uid := range uids {
+		if o, ok := byUID[uid]; ok {
+			result = append(result, o)
+			delete(byUID, uid) // повторяющиеся uid возвращаются один раз
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"readermicroservice/internal/config"
+
+	"github.com/lib/pq"
+)
+
+// SearchHit — заказ, найденный полнотекстовым поиском
+type SearchHit struct {
+	OrderUID string
+	Rank     float64
+	// Highlights — совпавшие поля ("delivery.name", "items.brand", ...) с найденными
+	// словами, обернутыми в <mark></mark>. У одного заказа может совпасть несколько товаров
+	Highlights map[string][]string
+}
+
+// SearchPage — страница результатов поиска. HasMore сообщает, есть ли следующая страница
+type SearchPage struct {
+	Hits    []SearchHit
+	HasMore bool
+}
+
+// searchQuery — поисковый запрос в синтаксисе websearch_to_tsquery: слова, "фразы",
+// or и -исключения. Конфигурация 'simple' не стеммит слова, поэтому одинаково
+// работает с именами, email и брендами на любом языке
+const searchQuery = "WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query) "
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+
+// Search ищет заказы по имени, email и городу получателя, бренду и названию товара.
+// Запрос сопоставляется с одним документом на заказ, поэтому слова могут совпасть
+// в разных полях и разных товарах. Заказы упорядочены по релевантности, затем по order_uid
+func (db *DB) Search(ctx context.Context, query string, limit, offset int) (SearchPage, error) {
+	// Лишняя строка показывает, есть ли следующая страница
+	rows, err := db.QueryContext(ctx, searchQuery+
+		"SELECT o.order_uid, ts_rank(o.search_vector, q.query) AS rank FROM orders o, q "+
+		"WHERE o.search_vector @@ q.query ORDER BY rank DESC, o.order_uid LIMIT $2 OFFSET $3",
+		query, limit+1, offset)
+	if err != nil {
+		config.RLogger.Println("Error while searching orders: ", err)
+		return SearchPage{}, fmt.Errorf("failed to search orders: %w", err)
+	}
+	defer rows.Close()
+
+	var page SearchPage
+	for rows.Next() {
+		var hit SearchHit
+		if err := rows.Scan(&hit.OrderUID, &hit.Rank); err != nil {
+			return SearchPage{}, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		page.Hits = append(page.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return SearchPage{}, fmt.Errorf("failed to iterate search hits: %w", err)
+	}
+	if len(page.Hits) > limit {
+		page.Hits = page.Hits[:limit]
+		page.HasMore = true
+	}
+	if len(page.Hits) == 0 {
+		return page, nil
+	}
+
+	if err := db.attachHighlights(ctx, query, page.Hits); err != nil {
+		config.RLogger.Println("Error while highlighting search hits: ", err)
+		return SearchPage{}, err
+	}
+	return page, nil
+}
+
+// attachHighlights заполняет Highlights одним запросом на всю страницу
+func (db *DB) attachHighlights(ctx context.Context, query string, hits []SearchHit) error {
+	uids := make([]string, len(hits))
+	byUID := make(map[string]*SearchHit, len(hits))
+	for i := range hits {
+		uids[i] = hits[i].OrderUID
+		byUID[hits[i].OrderUID] = &hits[i]
+	}
+
+	// Слова запроса могут быть разнесены по нескольким полям, и ни одно поле не совпадет
+	// с запросом целиком, поэтому подсвечивается каждое поле-кандидат, а остаются
+	// только фрагменты, в которых что-то отмечено
+	rows, err := db.QueryContext(ctx, searchQuery+
+		"SELECT h.order_uid, h.field, h.fragment FROM ("+
+		"SELECT f.order_uid, f.field, ts_headline('simple', f.value, q.query, $3) AS fragment FROM q, ("+
+		"SELECT d.order_uid, v.field, v.value FROM delivery d "+
+		"CROSS JOIN LATERAL (VALUES ('delivery.name', d.name), ('delivery.email', d.email), ('delivery.city', d.city)) AS v(field, value) "+
+		"WHERE d.order_uid = ANY($2) "+
+		"UNION ALL "+
+		"SELECT i.order_uid, v.field, v.value FROM items i "+
+		"CROSS JOIN LATERAL (VALUES ('items.brand', i.brand), ('items.name', i.name)) AS v(field, value) "+
+		"WHERE i.order_uid = ANY($2)"+
+		") f WHERE f.value <> ''"+
+		") h WHERE strpos(h.fragment, '<mark>') > 0",
+		query, pq.Array(uids), headlineOptions)
+	if err != nil {
+		return fmt.Errorf("failed to query search highlights: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uid, field, fragment string
+		if err := rows.Scan(&uid, &field, &fragment); err != nil {
+			return fmt.Errorf("failed to scan search highlight: %w", err)
+		}
+		hit := byUID[uid]
+		if hit.Highlights == nil {
+			hit.Highlights = make(map[string][]string)
+		}
+		if !slices.Contains(hit.Highlights[field], fragment) {
+			hit.Highlights[field] = append(hit.Highlights[field], fragment)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate search highlights: %w", err)
+	}
+	return nil
+}
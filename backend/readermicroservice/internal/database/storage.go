@@ -1,7 +1,12 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"readermicroservice/internal/config"
@@ -10,6 +15,52 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// orderColumns — столбцы orders в порядке, в котором они сканируются в models.Order
+const orderColumns = "order_uid, track_number, entry, locale, internal_signature, customer_id, " +
+	"delivery_service, shardkey, sm_id, date_created, oof_shard, discrepancies"
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOrder читает строку orders, выбранную по orderColumns
+func scanOrder(row rowScanner, order *models.Order) error {
+	var discrepancies []byte
+	err := row.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale,
+		&order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.Shardkey,
+		&order.SmID, &order.DateCreated, &order.OofShard, &discrepancies)
+	if err != nil {
+		return err
+	}
+
+	if len(discrepancies) > 0 {
+		if err := json.Unmarshal(discrepancies, &order.Discrepancies); err != nil {
+			return fmt.Errorf("failed to decode discrepancies: %w", err)
+		}
+	}
+	return nil
+}
+
+// encodeDiscrepancies возвращает значение для столбца discrepancies: NULL, если расхождений нет
+func encodeDiscrepancies(d []models.Discrepancy) (interface{}, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode discrepancies: %w", err)
+	}
+	return string(b), nil
+}
+
+// ErrNotFound возвращается, если заказа с указанным order_uid нет в БД
+var ErrNotFound = errors.New("order not found")
+
+// ErrStaleVersion возвращается Insert, если в БД уже сохранена более новая версия
+// заказа (по date_created), например при запоздалой повторной доставке из Kafka
+var ErrStaleVersion = errors.New("newer order version already stored")
+
 type DB struct {
 	*sql.DB
 }
@@ -37,18 +88,90 @@ func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-func (db *DB) Insert(data models.Order) error {
-	_, err := db.Exec("INSERT INTO orders "+
-		"(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard) "+
-		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+// Insert сохраняет заказ целиком в одной транзакции: при любой ошибке изменения откатываются.
+// Повторная доставка того же заказа ничего не меняет, а новая версия заказа
+// перезаписывает доставку, оплату и товары
+func (db *DB) Insert(data models.Order) (err error) {
+	hash, err := orderHash(data)
+	if err != nil {
+		config.RLogger.Println("Error while hashing order: ", err)
+		return err
+	}
+
+	discrepancies, err := encodeDiscrepancies(data.Discrepancies)
+	if err != nil {
+		config.RLogger.Println("Error while encoding discrepancies: ", err)
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		config.RLogger.Println("Error while starting transaction: ", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// После Commit откатывать нечего, даже если Insert возвращает ErrStaleVersion
+	committed := false
+	defer func() {
+		if err != nil && !committed {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.RLogger.Println("Error while rolling back transaction: ", rbErr)
+			}
+		}
+	}()
+
+	// Строка заказа обновляется, только если содержимое заказа изменилось и версия
+	// не старше сохраненной: запоздалая повторная доставка не затирает новый заказ
+	res, err := tx.Exec("INSERT INTO orders "+
+		"(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, discrepancies, payload_hash) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) "+
+		"ON CONFLICT (order_uid) DO UPDATE SET "+
+		"track_number = EXCLUDED.track_number, entry = EXCLUDED.entry, locale = EXCLUDED.locale, "+
+		"internal_signature = EXCLUDED.internal_signature, customer_id = EXCLUDED.customer_id, "+
+		"delivery_service = EXCLUDED.delivery_service, shardkey = EXCLUDED.shardkey, sm_id = EXCLUDED.sm_id, "+
+		"date_created = EXCLUDED.date_created, oof_shard = EXCLUDED.oof_shard, "+
+		"discrepancies = EXCLUDED.discrepancies, payload_hash = EXCLUDED.payload_hash "+
+		"WHERE orders.payload_hash IS DISTINCT FROM EXCLUDED.payload_hash "+
+		"AND (orders.date_created IS NULL OR EXCLUDED.date_created >= orders.date_created)",
 		data.OrderUID, data.TrackNumber, data.Entry, data.Locale, data.InternalSignature,
-		data.CustomerID, data.DeliveryService, data.Shardkey, data.SmID, data.DateCreated, data.OofShard)
+		data.CustomerID, data.DeliveryService, data.Shardkey, data.SmID, data.DateCreated, data.OofShard, discrepancies, hash)
 	if err != nil {
 		config.RLogger.Println("Error while inserting data to orders table: ", err)
 		return err
 	}
 
-	_, err = db.Exec("INSERT INTO delivery(order_uid, name, phone, zip, city, address, region, email)"+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		config.RLogger.Println("Error while reading affected rows: ", err)
+		return err
+	}
+	if affected == 0 {
+		// Сохранена та же версия заказа или более новая
+		var same bool
+		err = tx.QueryRow("SELECT COALESCE(payload_hash = $2, false) FROM orders WHERE order_uid = $1", data.OrderUID, hash).Scan(&same)
+		if err != nil {
+			config.RLogger.Println("Error while reading stored order version: ", err)
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			config.RLogger.Println("Error while committing transaction: ", err)
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		committed = true
+		if !same {
+			return ErrStaleVersion
+		}
+		return nil
+	}
+
+	// Удаляем связанные строки предыдущей версии заказа, если она была
+	for _, table := range []string{"items", "payments", "delivery"} {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE order_uid = $1", data.OrderUID); err != nil {
+			config.RLogger.Println("Error while deleting previous data from ", table, " table: ", err)
+			return err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO delivery(order_uid, name, phone, zip, city, address, region, email)"+
 		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", data.OrderUID, data.Delivery.Name, data.Delivery.Phone,
 		data.Delivery.Zip, data.Delivery.City, data.Delivery.Address, data.Delivery.Region, data.Delivery.Email)
 	if err != nil {
@@ -56,7 +179,7 @@ func (db *DB) Insert(data models.Order) error {
 		return err
 	}
 
-	_, err = db.Exec("INSERT INTO payments(order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) "+
+	_, err = tx.Exec("INSERT INTO payments(order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee) "+
 		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
 		data.OrderUID, data.Payment.Transaction, data.Payment.RequestID, data.Payment.Currency,
 		data.Payment.Provider, data.Payment.Amount, data.Payment.PaymentDt, data.Payment.Bank,
@@ -66,12 +189,17 @@ func (db *DB) Insert(data models.Order) error {
 		return err
 	}
 
-	// Вставка items — по одному запросу на каждую позицию
-	itemStmt := "INSERT INTO items(order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status) " +
-		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+	// Вставка items — один подготовленный запрос на все позиции
+	itemStmt, err := tx.Prepare("INSERT INTO items(order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)")
+	if err != nil {
+		config.RLogger.Println("Error while preparing items insert: ", err)
+		return err
+	}
+	defer itemStmt.Close()
+
 	for _, it := range data.Items {
-		_, err = db.Exec(itemStmt,
-			data.OrderUID, it.ChrtID, it.TrackNumber, it.Price, it.Rid, it.Name,
+		_, err = itemStmt.Exec(data.OrderUID, it.ChrtID, it.TrackNumber, it.Price, it.Rid, it.Name,
 			it.Sale, it.Size, it.TotalPrice, it.NmID, it.Brand, it.Status)
 		if err != nil {
 			config.RLogger.Println("Error while inserting data to items table: ", err)
@@ -79,15 +207,38 @@ func (db *DB) Insert(data models.Order) error {
 		}
 	}
 
+	// Поисковый вектор заказа собирается из delivery и всех items (миграция 007)
+	_, err = tx.Exec("UPDATE orders SET search_vector = order_search_vector(order_uid) WHERE order_uid = $1", data.OrderUID)
+	if err != nil {
+		config.RLogger.Println("Error while updating order search vector: ", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		config.RLogger.Println("Error while committing transaction: ", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+// orderHash вычисляет отпечаток содержимого заказа для распознавания повторных доставок
+func orderHash(data models.Order) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal order: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 func (db *DB) GetByUID(order_uid string) (*models.Order, error) {
-	row := db.QueryRow("SELECT * FROM orders WHERE order_uid = $1", order_uid)
+	row := db.QueryRow("SELECT "+orderColumns+" FROM orders WHERE order_uid = $1", order_uid)
 	var order models.Order
-	err := row.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale,
-		&order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.Shardkey,
-		&order.SmID, &order.DateCreated, &order.OofShard)
+	err := scanOrder(row, &order)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		config.RLogger.Println("Error scanning order: ", err)
 		return nil, err
@@ -139,75 +290,20 @@ func (db *DB) GetByUID(order_uid string) (*models.Order, error) {
 	return &order, nil
 }
 
+// GetAll загружает все заказы фиксированным числом запросов (по одному на таблицу)
+// и собирает их в памяти
 func (db *DB) GetAll() ([]models.Order, error) {
-	rows, err := db.Query("SELECT * FROM orders")
+	ctx := context.Background()
+
+	orders, err := db.queryOrders(ctx, "SELECT "+orderColumns+" FROM orders")
 	if err != nil {
 		config.RLogger.Println("Error while reading data from orders table: ", err)
 		return nil, err
 	}
 
-	orders := make([]models.Order, 0)
-
-	defer rows.Close()
-	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale,
-			&order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.Shardkey,
-			&order.SmID, &order.DateCreated, &order.OofShard)
-		if err != nil {
-			config.RLogger.Println("Error scanning order: ", err)
-			continue
-		}
-
-		// Получаем данные доставки
-		var delivery models.Delivery
-		row := db.QueryRow("SELECT name, phone, zip, city, address, region, email FROM delivery WHERE order_uid = $1", order.OrderUID)
-		err = row.Scan(&delivery.Name, &delivery.Phone, &delivery.Zip, &delivery.City,
-			&delivery.Address, &delivery.Region, &delivery.Email)
-		if err != nil {
-			config.RLogger.Println("Error getting delivery for order ", order.OrderUID, ": ", err)
-			continue
-		}
-		order.Delivery = delivery
-
-		// Получаем данные оплаты
-		var payment models.Payment
-		row = db.QueryRow("SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee FROM payments WHERE order_uid = $1", order.OrderUID)
-		err = row.Scan(&payment.Transaction, &payment.RequestID, &payment.Currency, &payment.Provider,
-			&payment.Amount, &payment.PaymentDt, &payment.Bank, &payment.DeliveryCost,
-			&payment.GoodsTotal, &payment.CustomFee)
-		if err != nil {
-			config.RLogger.Println("Error getting payment for order ", order.OrderUID, ": ", err)
-			continue
-		}
-		order.Payment = payment
-
-		// Получаем товары
-		itemRows, err := db.Query("SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status FROM items WHERE order_uid = $1", order.OrderUID)
-		if err != nil {
-			config.RLogger.Println("Error getting items for order ", order.OrderUID, ": ", err)
-			continue
-		}
-		defer itemRows.Close()
-
-		var items []models.Item
-		for itemRows.Next() {
-			var item models.Item
-			err := itemRows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name,
-				&item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
-			if err != nil {
-				config.RLogger.Println("Error scanning item for order ", order.OrderUID, ": ", err)
-				continue
-			}
-			items = append(items, item)
-		}
-		order.Items = items
-
-		orders = append(orders, order)
-	}
-
-	if err = rows.Err(); err != nil {
-		config.RLogger.Println("Error iterating orders: ", err)
+	orders, err = db.attachDetails(ctx, orders, "")
+	if err != nil {
+		config.RLogger.Println("Error while reading order details: ", err)
 		return nil, err
 	}
 
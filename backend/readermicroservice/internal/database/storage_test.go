@@ -1,12 +1,18 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"readermicroservice/internal/config"
 	"readermicroservice/internal/models"
+
+	"github.com/lib/pq"
 )
 
 func TestMain(m *testing.M) {
@@ -14,6 +20,21 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// cleanupOrders удаляет тестовые заказы до и после теста: заказы с датами в будущем,
+// оставшиеся от прошлых запусков, попадали бы в выборки по date_created
+func cleanupOrders(t *testing.T, db *DB, uids ...string) {
+	t.Helper()
+	remove := func() {
+		for _, table := range []string{"items", "payments", "delivery", "orders"} {
+			if _, err := db.Exec("DELETE FROM "+table+" WHERE order_uid = ANY($1)", pq.Array(uids)); err != nil {
+				t.Logf("Failed to clean up %s: %v", table, err)
+			}
+		}
+	}
+	remove()
+	t.Cleanup(remove)
+}
+
 func TestDB_Insert(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping database test in short mode")
@@ -63,3 +84,521 @@ func TestDB_Insert(t *testing.T) {
 		t.Errorf("Expected order UID test-integration-1, got %s", retrieved.OrderUID)
 	}
 }
+
+func TestDB_Insert_RollbackOnItemFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	db.Exec("DELETE FROM items WHERE order_uid = 'test-integration-2'")
+	db.Exec("DELETE FROM payments WHERE order_uid = 'test-integration-2'")
+	db.Exec("DELETE FROM delivery WHERE order_uid = 'test-integration-2'")
+	db.Exec("DELETE FROM orders WHERE order_uid = 'test-integration-2'")
+
+	// rid длиннее VARCHAR(100) — вставка items упадет после orders, delivery и payments
+	order := models.Order{
+		OrderUID:    "test-integration-2",
+		TrackNumber: "WB-TEST-2",
+		Items: []models.Item{
+			{Rid: strings.Repeat("x", 200)},
+		},
+	}
+
+	if err := db.Insert(order); err == nil {
+		t.Fatal("Expected insert to fail")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM orders WHERE order_uid = 'test-integration-2'").Scan(&count); err != nil {
+		t.Fatalf("Failed to count orders: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected order to be rolled back, found %d rows", count)
+	}
+
+	if err := db.Insert(models.Order{OrderUID: "test-integration-2"}); err != nil {
+		t.Errorf("Expected retry after rollback to succeed, got %v", err)
+	}
+}
+
+func TestDB_Insert_Upsert(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	db.Exec("DELETE FROM items WHERE order_uid = 'test-integration-3'")
+	db.Exec("DELETE FROM payments WHERE order_uid = 'test-integration-3'")
+	db.Exec("DELETE FROM delivery WHERE order_uid = 'test-integration-3'")
+	db.Exec("DELETE FROM orders WHERE order_uid = 'test-integration-3'")
+
+	order := models.Order{
+		OrderUID:    "test-integration-3",
+		TrackNumber: "WB-TEST-3",
+		Items:       []models.Item{{Rid: "rid-1", TrackNumber: "WB-TEST-3"}},
+	}
+
+	if err := db.Insert(order); err != nil {
+		t.Fatalf("Failed to insert order: %v", err)
+	}
+	// Повторная доставка той же версии — no-op
+	if err := db.Insert(order); err != nil {
+		t.Fatalf("Failed to re-insert identical order: %v", err)
+	}
+
+	order.Delivery.City = "Moscow"
+	order.Items = append(order.Items, models.Item{Rid: "rid-2", TrackNumber: "WB-TEST-3"})
+	if err := db.Insert(order); err != nil {
+		t.Fatalf("Failed to insert updated order: %v", err)
+	}
+
+	retrieved, err := db.GetByUID("test-integration-3")
+	if err != nil {
+		t.Fatalf("Failed to get order: %v", err)
+	}
+	if retrieved.Delivery.City != "Moscow" {
+		t.Errorf("Expected updated city Moscow, got %s", retrieved.Delivery.City)
+	}
+	if len(retrieved.Items) != 2 {
+		t.Errorf("Expected 2 items after update, got %d", len(retrieved.Items))
+	}
+}
+
+func TestDB_Insert_StaleRedelivery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	db.Exec("DELETE FROM items WHERE order_uid = 'test-integration-9'")
+	db.Exec("DELETE FROM payments WHERE order_uid = 'test-integration-9'")
+	db.Exec("DELETE FROM delivery WHERE order_uid = 'test-integration-9'")
+	db.Exec("DELETE FROM orders WHERE order_uid = 'test-integration-9'")
+
+	older := models.Order{
+		OrderUID:    "test-integration-9",
+		TrackNumber: "WB-TEST-9",
+		DateCreated: time.Date(2021, 11, 26, 6, 0, 0, 0, time.UTC),
+		Delivery:    models.Delivery{City: "Kazan"},
+	}
+	newer := older
+	newer.DateCreated = older.DateCreated.Add(time.Hour)
+	newer.Delivery.City = "Moscow"
+
+	if err := db.Insert(newer); err != nil {
+		t.Fatalf("Failed to insert order: %v", err)
+	}
+	// Старая версия пришла из Kafka после новой
+	if err := db.Insert(older); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("Expected ErrStaleVersion for older version, got %v", err)
+	}
+	// Повторная доставка сохраненной версии — по-прежнему no-op без ошибки
+	if err := db.Insert(newer); err != nil {
+		t.Errorf("Expected redelivery of stored version to succeed, got %v", err)
+	}
+
+	retrieved, err := db.GetByUID("test-integration-9")
+	if err != nil {
+		t.Fatalf("Failed to get order: %v", err)
+	}
+	if retrieved.Delivery.City != "Moscow" || !retrieved.DateCreated.Equal(newer.DateCreated) {
+		t.Errorf("Expected newer version to be kept, got %+v", retrieved)
+	}
+}
+
+func TestDB_GetAll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	for _, uid := range []string{"test-integration-4", "test-integration-5"} {
+		order := models.Order{
+			OrderUID:    uid,
+			TrackNumber: "WB-" + uid,
+			Payment:     models.Payment{Transaction: uid},
+			Items: []models.Item{
+				{Rid: uid + "-1", TrackNumber: "WB-" + uid},
+				{Rid: uid + "-2", TrackNumber: "WB-" + uid},
+			},
+		}
+		if err := db.Insert(order); err != nil {
+			t.Fatalf("Failed to insert order %s: %v", uid, err)
+		}
+	}
+
+	orders, err := db.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get orders: %v", err)
+	}
+
+	found := 0
+	for _, o := range orders {
+		if o.OrderUID != "test-integration-4" && o.OrderUID != "test-integration-5" {
+			continue
+		}
+		found++
+		if o.Payment.Transaction != o.OrderUID {
+			t.Errorf("Order %s: expected payment transaction %s, got %s", o.OrderUID, o.OrderUID, o.Payment.Transaction)
+		}
+		if len(o.Items) != 2 || o.Items[0].Rid != o.OrderUID+"-1" {
+			t.Errorf("Order %s: unexpected items %+v", o.OrderUID, o.Items)
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected both test orders in GetAll, found %d", found)
+	}
+}
+
+func TestDB_IterateRecent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Даты в будущем, чтобы тестовые заказы гарантированно были самыми новыми
+	base := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	uids := []string{"test-integration-6", "test-integration-7", "test-integration-8"}
+	cleanupOrders(t, db, uids...)
+	for i, uid := range uids {
+		order := models.Order{
+			OrderUID:    uid,
+			DateCreated: base.Add(time.Duration(i) * time.Hour),
+		}
+		if err := db.Insert(order); err != nil {
+			t.Fatalf("Failed to insert order %s: %v", uid, err)
+		}
+	}
+
+	var got []string
+	err = db.IterateRecent(context.Background(), 2, func(o models.Order) bool {
+		got = append(got, o.OrderUID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Failed to iterate orders: %v", err)
+	}
+
+	if len(got) != 2 || got[0] != "test-integration-8" || got[1] != "test-integration-7" {
+		t.Errorf("Expected [test-integration-8 test-integration-7], got %v", got)
+	}
+}
+
+func TestDB_ListOrders(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	cleanupOrders(t, db, "test-list-1", "test-list-2", "test-list-3", "test-list-4")
+
+	base := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, uid := range []string{"test-list-1", "test-list-2", "test-list-3", "test-list-4"} {
+		currency := "USD"
+		if i == 3 {
+			currency = "EUR"
+		}
+		order := models.Order{
+			OrderUID:    uid,
+			CustomerID:  "test-list-customer",
+			DateCreated: base.Add(time.Duration(i) * time.Hour),
+			Payment:     models.Payment{Transaction: uid, Currency: currency},
+		}
+		if err := db.Insert(order); err != nil {
+			t.Fatalf("Failed to insert order %s: %v", uid, err)
+		}
+	}
+
+	filter := OrderFilter{CustomerID: "test-list-customer", Currency: "USD"}
+	var got []string
+	var after *Cursor
+	for pages := 0; pages < 5; pages++ {
+		page, err := db.ListOrders(context.Background(), filter, after, 2)
+		if err != nil {
+			t.Fatalf("Failed to list orders: %v", err)
+		}
+		for _, o := range page.Orders {
+			got = append(got, o.OrderUID)
+		}
+		if page.Next == nil {
+			break
+		}
+		after = page.Next
+	}
+
+	want := []string{"test-list-3", "test-list-2", "test-list-1"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestDB_FindOrderUIDs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	cleanupOrders(t, db, "test-lookup-1", "test-lookup-2")
+
+	base := time.Date(2098, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, uid := range []string{"test-lookup-1", "test-lookup-2"} {
+		order := models.Order{
+			OrderUID:    uid,
+			TrackNumber: "test-lookup-track",
+			DateCreated: base.Add(time.Duration(i) * time.Hour),
+			Payment:     models.Payment{Transaction: uid + "-tx"},
+			Items:       []models.Item{{ChrtID: i, Rid: uid + "-rid"}},
+		}
+		if err := db.Insert(order); err != nil {
+			t.Fatalf("Failed to insert order %s: %v", uid, err)
+		}
+	}
+
+	ctx := context.Background()
+	uids, err := db.FindOrderUIDs(ctx, LookupTrackNumber, "test-lookup-track")
+	if err != nil || len(uids) != 2 || uids[0] != "test-lookup-2" {
+		t.Errorf("Expected both orders newest first, got %v (%v)", uids, err)
+	}
+	if uids, err := db.FindOrderUIDs(ctx, LookupTransaction, "test-lookup-1-tx"); err != nil || len(uids) != 1 {
+		t.Errorf("Expected one order by transaction, got %v (%v)", uids, err)
+	}
+	if uids, err := db.FindOrderUIDs(ctx, LookupItemRid, "test-lookup-2-rid"); err != nil || len(uids) != 1 {
+		t.Errorf("Expected one order by rid, got %v (%v)", uids, err)
+	}
+
+	orders, err := db.GetByUIDs(ctx, []string{"test-lookup-2", "missing", "test-lookup-1"})
+	if err != nil {
+		t.Fatalf("Failed to get orders: %v", err)
+	}
+	if len(orders) != 2 || orders[0].OrderUID != "test-lookup-2" || len(orders[1].Items) != 1 {
+		t.Errorf("Expected orders in requested order with items, got %+v", orders)
+	}
+}
+
+func TestDB_GetCustomerSummary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	cleanupOrders(t, db, "test-summary-1", "test-summary-2", "test-summary-3")
+
+	base := time.Date(2097, 1, 1, 0, 0, 0, 0, time.UTC)
+	payments := []models.Payment{
+		{Transaction: "test-summary-1", Currency: "USD", Amount: 100},
+		{Transaction: "test-summary-2", Currency: "USD", Amount: 250},
+		{Transaction: "test-summary-3", Currency: "EUR", Amount: 40},
+	}
+	for i, p := range payments {
+		order := models.Order{
+			OrderUID:    p.Transaction,
+			CustomerID:  "test-summary-customer",
+			DateCreated: base.Add(time.Duration(i) * time.Hour),
+			Payment:     p,
+		}
+		if err := db.Insert(order); err != nil {
+			t.Fatalf("Failed to insert order %s: %v", order.OrderUID, err)
+		}
+	}
+
+	summary, err := db.GetCustomerSummary(context.Background(), "test-summary-customer")
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+	if summary.OrderCount != 3 || summary.TotalSpend["USD"] != 350 || summary.TotalSpend["EUR"] != 40 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if summary.FirstOrderAt == nil || !summary.FirstOrderAt.Equal(base) ||
+		summary.LastOrderAt == nil || !summary.LastOrderAt.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("Unexpected order dates %v - %v", summary.FirstOrderAt, summary.LastOrderAt)
+	}
+}
+
+func TestDB_Search(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping database test in short mode")
+	}
+
+	cfg := config.DBConfig{
+		Host:     "localhost",
+		Port:     5433,
+		User:     "testuser",
+		Password: "testpassword",
+		Database: "testdatabase",
+	}
+
+	db, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	cleanupOrders(t, db, "test-search-1", "test-search-2")
+
+	orders := []models.Order{
+		{
+			OrderUID: "test-search-1",
+			Delivery: models.Delivery{Name: "Testsearch Ivanov", City: "Kazan", Email: "ivanov@example.com"},
+			Items:    []models.Item{{ChrtID: 1, Name: "Mascaras", Brand: "Testsearchbrand"}},
+		},
+		{
+			OrderUID: "test-search-2",
+			Delivery: models.Delivery{Name: "Petr Petrov", City: "Testsearch"},
+			Items:    []models.Item{{ChrtID: 2, Name: "Lipstick", Brand: "Other"}},
+		},
+	}
+	for _, order := range orders {
+		if err := db.Insert(order); err != nil {
+			t.Fatalf("Failed to insert order %s: %v", order.OrderUID, err)
+		}
+	}
+
+	ctx := context.Background()
+	page, err := db.Search(ctx, "testsearch", 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(page.Hits) != 1 || !page.HasMore || page.Hits[0].OrderUID != "test-search-1" {
+		t.Fatalf("Expected best match first with more pages, got %+v", page)
+	}
+	if got := page.Hits[0].Highlights["delivery.name"]; len(got) != 1 || got[0] != "<mark>Testsearch</mark> Ivanov" {
+		t.Errorf("Expected highlighted name, got %v", page.Hits[0].Highlights)
+	}
+
+	page, err = db.Search(ctx, "testsearch", 1, 1)
+	if err != nil || len(page.Hits) != 1 || page.HasMore || page.Hits[0].OrderUID != "test-search-2" {
+		t.Errorf("Expected second match on last page, got %+v (%v)", page, err)
+	}
+
+	// Слова запроса совпадают в разных таблицах: имя в delivery, название в items
+	page, err = db.Search(ctx, "testsearch mascaras", 10, 0)
+	if err != nil || len(page.Hits) != 1 || page.Hits[0].OrderUID != "test-search-1" {
+		t.Fatalf("Expected recipient and product terms to match one order, got %+v (%v)", page, err)
+	}
+
+	// Ни одно поле не совпадает с запросом целиком, но каждое подсвечивается
+	page, err = db.Search(ctx, "ivanov kazan", 10, 0)
+	if err != nil || len(page.Hits) != 1 {
+		t.Fatalf("Expected one order for name and city, got %+v (%v)", page, err)
+	}
+	want := map[string]string{
+		"delivery.name": "Testsearch <mark>Ivanov</mark>",
+		"delivery.city": "<mark>Kazan</mark>",
+	}
+	for field, fragment := range want {
+		if got := page.Hits[0].Highlights[field]; len(got) != 1 || got[0] != fragment {
+			t.Errorf("Expected %s highlight %q, got %v", field, fragment, got)
+		}
+	}
+	// Email разбирается парсером как одно слово и с "ivanov" не совпадает
+	if len(page.Hits[0].Highlights) != len(want) {
+		t.Errorf("Expected unmatched fields without highlights, got %v", page.Hits[0].Highlights)
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"readermicroservice/internal/cache"
+	"readermicroservice/internal/config"
+)
+
+// Admin — служебные маршруты для управления кэшем. Все запросы должны нести
+// заголовок "Authorization: Bearer <token>"
+type Admin struct {
+	cache     cache.OrderCache
+	db        cache.OrderDatabase
+	tokenHash [sha256.Size]byte
+	reloading sync.Mutex
+}
+
+func NewAdmin(cache cache.OrderCache, db cache.OrderDatabase, token string) *Admin {
+	return &Admin{
+		cache:     cache,
+		db:        db,
+		tokenHash: sha256.Sum256([]byte(token)),
+	}
+}
+
+// Register добавляет маршруты администрирования в mux
+func (a *Admin) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/cache/entries", a.authorize(a.EntriesHandler))
+	mux.HandleFunc("DELETE /admin/cache/entries", a.authorize(a.FlushHandler))
+	mux.HandleFunc("DELETE /admin/cache/entries/{order_uid}", a.authorize(a.EvictHandler))
+	mux.HandleFunc("POST /admin/cache/reload", a.authorize(a.ReloadHandler))
+}
+
+// authorize пропускает запрос, только если Bearer-токен совпадает с настроенным.
+// Сравниваются хеши токенов за постоянное время, чтобы не раскрывать ни содержимое,
+// ни длину токена
+func (a *Admin) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		hash := sha256.Sum256([]byte(token))
+		if !ok || subtle.ConstantTimeCompare(hash[:], a.tokenHash[:]) != 1 {
+			config.RLogger.Printf("Unauthorized admin request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// EntriesHandler отдает список закэшированных заказов с возрастом и временем последнего доступа
+func (a *Admin) EntriesHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, a.cache.Entries())
+}
+
+// EvictHandler удаляет один заказ из кэша
+func (a *Admin) EvictHandler(w http.ResponseWriter, r *http.Request) {
+	orderUID := r.PathValue("order_uid")
+	if !a.cache.Delete(orderUID) {
+		http.Error(w, "Order not cached", http.StatusNotFound)
+		return
+	}
+
+	config.RLogger.Printf("Admin evicted order %s from cache", orderUID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// FlushHandler очищает кэш
+func (a *Admin) FlushHandler(w http.ResponseWriter, r *http.Request) {
+	a.cache.Flush()
+
+	config.RLogger.Println("Admin flushed cache")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReloadHandler заново заполняет кэш из БД. Одновременно выполняется только одна перезагрузка
+func (a *Admin) ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.reloading.TryLock() {
+		http.Error(w, "Cache reload already in progress", http.StatusConflict)
+		return
+	}
+	defer a.reloading.Unlock()
+
+	config.RLogger.Println("Admin triggered cache reload from DB")
+	a.cache.ResetDB(a.db)
+	respondWithJSON(w, http.StatusOK, a.cache.GetStats())
+}
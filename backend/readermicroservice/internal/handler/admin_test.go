@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/cache"
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+type recentDB struct {
+	orders []models.Order
+}
+
+func (d *recentDB) IterateRecent(ctx context.Context, limit int, fn func(models.Order) bool) error {
+	for _, o := range d.orders {
+		if !fn(o) {
+			break
+		}
+	}
+	return nil
+}
+
+func newTestAdmin(t *testing.T, db cache.OrderDatabase) (*http.ServeMux, cache.OrderCache) {
+	t.Helper()
+
+	c := cache.New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+	})
+	t.Cleanup(c.StopCleanup)
+
+	mux := http.NewServeMux()
+	NewAdmin(c, db, "secret").Register(mux)
+	return mux, c
+}
+
+func adminRequest(mux *http.ServeMux, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	mux, _ := newTestAdmin(t, &recentDB{})
+
+	for _, token := range []string{"", "wrong", "secret2"} {
+		if w := adminRequest(mux, "GET", "/admin/cache/entries", token); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for token %q, got %d", token, w.Code)
+		}
+	}
+	if w := adminRequest(mux, "GET", "/admin/cache/entries", "secret"); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with valid token, got %d", w.Code)
+	}
+}
+
+func TestAdmin_EntriesEvictFlush(t *testing.T) {
+	mux, c := newTestAdmin(t, &recentDB{})
+	c.Add(models.Order{OrderUID: "a"})
+	c.Add(models.Order{OrderUID: "b"})
+
+	w := adminRequest(mux, "GET", "/admin/cache/entries", "secret")
+	var entries []map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&entries); err != nil {
+		t.Fatalf("Failed to decode entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0]["last_access"] == nil || entries[0]["age_ns"] == nil {
+		t.Errorf("Unexpected entries %v", entries)
+	}
+
+	if w := adminRequest(mux, "DELETE", "/admin/cache/entries/a", "secret"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w := adminRequest(mux, "DELETE", "/admin/cache/entries/a", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for uncached order, got %d", w.Code)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a to be evicted")
+	}
+
+	if w := adminRequest(mux, "DELETE", "/admin/cache/entries", "secret"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected cache to be flushed")
+	}
+}
+
+func TestAdmin_Reload(t *testing.T) {
+	mux, c := newTestAdmin(t, &recentDB{orders: []models.Order{{OrderUID: "from-db"}}})
+	c.Add(models.Order{OrderUID: "stale"})
+
+	w := adminRequest(mux, "POST", "/admin/cache/reload", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if _, ok := c.Get("from-db"); !ok {
+		t.Error("Expected cache to be reloaded from DB")
+	}
+	if _, ok := c.Get("stale"); ok {
+		t.Error("Expected reload to replace cache contents")
+	}
+}
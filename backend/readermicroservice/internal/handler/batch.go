@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+const (
+	// maxBatchSize — сколько order_uid самое большее принимает POST /orders:batchGet
+	maxBatchSize = 1000
+	// maxBatchBodyBytes с запасом вмещает maxBatchSize идентификаторов
+	maxBatchBodyBytes = 1 << 20
+)
+
+// batchGetRequest — тело POST /orders:batchGet
+type batchGetRequest struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+// batchGetResponse — найденные заказы в порядке запроса и order_uid, которых нет в БД
+type batchGetResponse struct {
+	Orders  []models.Order `json:"orders"`
+	Missing []string       `json:"missing"`
+}
+
+// BatchGetHandler отдает заказы по списку order_uid: POST /orders:batchGet.
+// Попадания берутся из кэша, промахи загружаются из БД одним набором запросов
+func (h *Handler) BatchGetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	uids, err := decodeBatchGet(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orders, missing, err := h.ordersByUID(r.Context(), uids)
+	if err != nil {
+		config.RLogger.Printf("Error loading batch of %d orders: %v", len(uids), err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if missing == nil {
+		missing = []string{}
+	}
+
+	respondWithJSON(w, http.StatusOK, batchGetResponse{Orders: orders, Missing: missing})
+}
+
+func decodeBatchGet(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	var req batchGetRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, errors.New("request body must be a JSON object with order_uids")
+	}
+
+	if len(req.OrderUIDs) == 0 {
+		return nil, errors.New("order_uids must not be empty")
+	}
+	if len(req.OrderUIDs) > maxBatchSize {
+		return nil, errors.New("order_uids must contain at most " + strconv.Itoa(maxBatchSize) + " items")
+	}
+	for _, uid := range req.OrderUIDs {
+		if uid == "" {
+			return nil, errors.New("order_uids must not contain empty values")
+		}
+	}
+	return req.OrderUIDs, nil
+}
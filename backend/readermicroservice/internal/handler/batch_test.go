@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/cache"
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+func TestHandler_BatchGet(t *testing.T) {
+	var calls [][]string
+	db := &mockDB{
+		getByUIDsFunc: func(uids []string) ([]models.Order, error) {
+			calls = append(calls, uids)
+			return []models.Order{{OrderUID: "b"}}, nil
+		},
+	}
+
+	c := cache.New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		NegativeTTL:     time.Minute,
+		NegativeMaxSize: 10,
+	})
+	defer c.StopCleanup()
+	c.Add(models.Order{OrderUID: "a"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orders:batchGet", New(c, db).BatchGetHandler)
+
+	w := httptest.NewRecorder()
+	body := `{"order_uids": ["a", "b", "x", "a", "x", "b"]}`
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/orders:batchGet", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp batchGetResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Orders) != 2 || resp.Orders[0].OrderUID != "a" || resp.Orders[1].OrderUID != "b" {
+		t.Errorf("Expected orders a and b in request order, got %+v", resp.Orders)
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "x" {
+		t.Errorf("Expected x to be missing, got %v", resp.Missing)
+	}
+	if len(calls) != 1 || len(calls[0]) != 2 {
+		t.Errorf("Expected one DB query for distinct cache misses, got %v", calls)
+	}
+	if !c.IsMissing("x") {
+		t.Error("Expected missing order to be marked in negative cache")
+	}
+
+	// Повторный запрос обслуживается из кэша целиком
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/orders:batchGet", strings.NewReader(body)))
+	if w.Code != http.StatusOK || len(calls) != 1 {
+		t.Errorf("Expected second batch to be served from cache, got status %d and DB calls %v", w.Code, calls)
+	}
+}
+
+func TestHandler_BatchGet_BadRequest(t *testing.T) {
+	h := New(nil, &mockDB{})
+	tooMany := `{"order_uids": [` + strings.Repeat(`"x",`, maxBatchSize) + `"x"]}`
+	for _, body := range []string{``, `[]`, `{"order_uids": []}`, `{"order_uids": [""]}`, `{"uids": ["a"]}`, tooMany} {
+		w := httptest.NewRecorder()
+		h.BatchGetHandler(w, httptest.NewRequest("POST", "/orders:batchGet", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%.40q: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.BatchGetHandler(w, httptest.NewRequest("GET", "/orders:batchGet", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+// customerOrdersResponse — ответ GET /customers/{customer_id}/orders. Сводка
+// относится ко всем заказам покупателя, а не только к текущей странице
+type customerOrdersResponse struct {
+	CustomerID string                   `json:"customer_id"`
+	Summary    database.CustomerSummary `json:"summary"`
+	Orders     []models.Order           `json:"orders"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// CustomerOrdersHandler отдает историю заказов покупателя от новых к старым
+// со сводкой; страницы выбираются параметрами limit и cursor, как в GET /orders
+func (h *Handler) CustomerOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	customerID := r.PathValue("customer_id")
+	if customerID == "" {
+		http.Error(w, "Customer ID is required", http.StatusBadRequest)
+		return
+	}
+	limit, after, err := parsePage(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.db.GetCustomerSummary(r.Context(), customerID)
+	if err != nil {
+		config.RLogger.Printf("Error reading summary for customer %s: %v", customerID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if summary.OrderCount == 0 {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	page, err := h.db.ListOrders(r.Context(), database.OrderFilter{CustomerID: customerID}, after, limit)
+	if err != nil {
+		config.RLogger.Printf("Error listing orders for customer %s: %v", customerID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, customerOrdersResponse{
+		CustomerID: customerID,
+		Summary:    summary,
+		Orders:     page.Orders,
+		NextCursor: encodeCursor(page.Next),
+	})
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+func newCustomerMux(h *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/customers/{customer_id}/orders", h.CustomerOrdersHandler)
+	return mux
+}
+
+func TestHandler_CustomerOrders(t *testing.T) {
+	first := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)
+
+	var gotFilter database.OrderFilter
+	db := &mockDB{
+		summaryFunc: func(customerID string) (database.CustomerSummary, error) {
+			return database.CustomerSummary{
+				OrderCount:   3,
+				TotalSpend:   map[string]int64{"USD": 1817, "RUB": 500},
+				FirstOrderAt: &first,
+				LastOrderAt:  &last,
+			}, nil
+		},
+		listOrdersFunc: func(filter database.OrderFilter, after *database.Cursor, limit int) (database.OrderPage, error) {
+			gotFilter = filter
+			return database.OrderPage{
+				Orders: []models.Order{{OrderUID: "b563feb7b2b84b6test", CustomerID: filter.CustomerID}},
+				Next:   &database.Cursor{DateCreated: last, OrderUID: "b563feb7b2b84b6test"},
+			}, nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	newCustomerMux(New(nil, db)).ServeHTTP(w, httptest.NewRequest("GET", "/customers/test/orders?limit=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if gotFilter.CustomerID != "test" {
+		t.Errorf("Expected orders filtered by customer, got %+v", gotFilter)
+	}
+
+	var resp customerOrdersResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.CustomerID != "test" || resp.Summary.OrderCount != 3 || resp.Summary.TotalSpend["USD"] != 1817 {
+		t.Errorf("Unexpected summary %+v", resp)
+	}
+	if resp.Summary.LastOrderAt == nil || !resp.Summary.LastOrderAt.Equal(last) {
+		t.Errorf("Expected last order date %v, got %v", last, resp.Summary.LastOrderAt)
+	}
+	if len(resp.Orders) != 1 || resp.NextCursor == "" {
+		t.Errorf("Expected one order and next cursor, got %+v", resp)
+	}
+}
+
+func TestHandler_CustomerOrders_UnknownCustomer(t *testing.T) {
+	w := httptest.NewRecorder()
+	newCustomerMux(New(nil, &mockDB{})).ServeHTTP(w, httptest.NewRequest("GET", "/customers/unknown/orders", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
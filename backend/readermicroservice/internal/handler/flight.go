@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"sync"
+
+	"readermicroservice/internal/models"
+)
+
+// flightCall — загрузка заказа из БД, результат которой ждут все конкурентные запросы
+type flightCall struct {
+	wg    sync.WaitGroup
+	order *models.Order
+	err   error
+}
+
+// flightGroup объединяет конкурентные промахи кэша по одному order_uid:
+// пока загрузка выполняется, остальные запросы ждут ее результат, а не идут в БД
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do выполняет fn для ключа не больше одного раза одновременно. shared == true,
+// если результат получен от загрузки, запущенной другим запросом
+func (g *flightGroup) do(key string, fn func() (*models.Order, error)) (order *models.Order, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.order, c.err, true
+	}
+
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// Ключ освобождается и при панике в fn, чтобы ожидающие не зависли
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.order, c.err = fn()
+	return c.order, c.err, false
+}
@@ -2,17 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
 	"readermicroservice/internal/cache"
 	"readermicroservice/internal/config"
 	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
 )
 
 type Handler struct {
-	cache cache.OrderCache
-	db    database.Database
+	cache   cache.OrderCache
+	db      database.Database
+	flights flightGroup
 }
 
 func New(cache cache.OrderCache, db database.Database) *Handler {
@@ -38,23 +41,66 @@ func (h *Handler) OrderHandler(w http.ResponseWriter, r *http.Request) {
 	// Try to get from cache first
 	if order, found := h.cache.Get(orderUID); found {
 		config.RLogger.Printf("Cache hit for order: %s", orderUID)
-		h.respondWithJSON(w, http.StatusOK, order)
+		respondWithJSON(w, http.StatusOK, order)
+		return
+	}
+
+	// Known to be missing: don't query the database again
+	if h.cache.IsMissing(orderUID) {
+		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
 	// If not in cache, get from database
-	config.RLogger.Printf("Cache miss for order: %s, querying database", orderUID)
-	order, err := h.db.GetByUID(orderUID)
-	if err != nil {
-		config.RLogger.Printf("Order not found in database: %s, error: %v", orderUID, err)
+	order, err := h.loadOrder(orderUID)
+	if errors.Is(err, database.ErrNotFound) {
+		config.RLogger.Printf("Order not found in database: %s", orderUID)
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		config.RLogger.Printf("Error loading order %s from database: %v", orderUID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	h.respondWithJSON(w, http.StatusOK, order)
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// CacheStatsHandler отдает статистику кэша: заполненность, попадания, промахи,
+// вставки, вытеснения и среднее время чтения
+func (h *Handler) CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.cache.GetStats())
+}
+
+// loadOrder загружает заказ из БД при промахе кэша. Конкурентные промахи по одному
+// order_uid объединяются в один запрос к БД, найденный заказ кладется в кэш,
+// а отсутствующий отмечается в негативном кэше
+func (h *Handler) loadOrder(orderUID string) (*models.Order, error) {
+	order, err, shared := h.flights.do(orderUID, func() (*models.Order, error) {
+		config.RLogger.Printf("Cache miss for order: %s, querying database", orderUID)
+		order, err := h.db.GetByUID(orderUID)
+		if errors.Is(err, database.ErrNotFound) {
+			h.cache.MarkMissing(orderUID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.cache.AddIfAbsent(*order)
+		return order, nil
+	})
+	if shared {
+		config.RLogger.Printf("Cache miss for order: %s, joined in-flight database query", orderUID)
+	}
+	return order, err
 }
 
-func (h *Handler) respondWithJSON(w http.ResponseWriter, status int, data interface{}) {
+func respondWithJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
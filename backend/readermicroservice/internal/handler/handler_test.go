@@ -1,20 +1,30 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"readermicroservice/internal/cache"
 	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
 	"readermicroservice/internal/models"
 )
 
 type mockDB struct {
-	getByUIDFunc func(string) (*models.Order, error)
+	getByUIDFunc   func(string) (*models.Order, error)
+	listOrdersFunc func(database.OrderFilter, *database.Cursor, int) (database.OrderPage, error)
+	getByUIDsFunc  func([]string) ([]models.Order, error)
+	findUIDsFunc   func(database.Lookup, string) ([]string, error)
+	summaryFunc    func(string) (database.CustomerSummary, error)
+	searchFunc     func(string, int, int) (database.SearchPage, error)
 }
 
 func (m *mockDB) GetByUID(uid string) (*models.Order, error) {
@@ -29,6 +39,43 @@ func (m *mockDB) GetAll() ([]models.Order, error) { return nil, nil }
 func (m *mockDB) Close() error                    { return nil }
 func (m *mockDB) Ping() error                     { return nil }
 
+func (m *mockDB) ListOrders(ctx context.Context, filter database.OrderFilter, after *database.Cursor, limit int) (database.OrderPage, error) {
+	if m.listOrdersFunc != nil {
+		return m.listOrdersFunc(filter, after, limit)
+	}
+	return database.OrderPage{}, nil
+}
+
+func (m *mockDB) GetByUIDs(ctx context.Context, uids []string) ([]models.Order, error) {
+	if m.getByUIDsFunc != nil {
+		return m.getByUIDsFunc(uids)
+	}
+	return nil, nil
+}
+
+func (m *mockDB) FindOrderUIDs(ctx context.Context, by database.Lookup, value string) ([]string, error) {
+	if m.findUIDsFunc != nil {
+		return m.findUIDsFunc(by, value)
+	}
+	return nil, nil
+}
+
+func (m *mockDB) GetCustomerSummary(ctx context.Context, customerID string) (database.CustomerSummary, error) {
+	if m.summaryFunc != nil {
+		return m.summaryFunc(customerID)
+	}
+	return database.CustomerSummary{}, nil
+}
+
+func (m *mockDB) Search(ctx context.Context, query string, limit, offset int) (database.SearchPage, error) {
+	if m.searchFunc != nil {
+		return m.searchFunc(query, limit, offset)
+	}
+	return database.SearchPage{}, nil
+}
+
+func (m *mockDB) IterateRecent(context.Context, int, func(models.Order) bool) error { return nil }
+
 func TestMain(m *testing.M) {
 	config.RLogger = log.New(os.Stdout, "TEST: ", log.LstdFlags)
 	os.Exit(m.Run())
@@ -69,3 +116,165 @@ func TestHandler_GetOrder_CacheMiss_DBHit(t *testing.T) {
 		t.Errorf("Expected test-123, got %s", order.OrderUID)
 	}
 }
+
+func TestHandler_GetOrder_CacheMiss_PopulatesCache(t *testing.T) {
+	calls := 0
+	mockDB := &mockDB{
+		getByUIDFunc: func(uid string) (*models.Order, error) {
+			calls++
+			return &models.Order{OrderUID: uid}, nil
+		},
+	}
+
+	cache := cache.New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+	})
+	defer cache.StopCleanup()
+
+	handler := New(cache, mockDB)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.OrderHandler(w, httptest.NewRequest("GET", "/order/test-123", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected 1 database query, got %d", calls)
+	}
+}
+
+func TestHandler_GetOrder_CoalescesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	mockDB := &mockDB{
+		getByUIDFunc: func(uid string) (*models.Order, error) {
+			calls.Add(1)
+			<-release
+			return &models.Order{OrderUID: uid}, nil
+		},
+	}
+
+	cache := cache.New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+	})
+	defer cache.StopCleanup()
+
+	handler := New(cache, mockDB)
+
+	const requests = 20
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.OrderHandler(w, httptest.NewRequest("GET", "/order/hot-order", nil))
+			codes <- w.Code
+		}()
+	}
+
+	// Даем запросам дойти до ожидания общей загрузки
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", code)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected 1 database query for concurrent misses, got %d", n)
+	}
+}
+
+func TestHandler_GetOrder_NegativeCache(t *testing.T) {
+	calls := 0
+	mockDB := &mockDB{
+		getByUIDFunc: func(uid string) (*models.Order, error) {
+			calls++
+			return nil, database.ErrNotFound
+		},
+	}
+
+	cache := cache.New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		NegativeTTL:     time.Minute,
+		NegativeMaxSize: 10,
+	})
+	defer cache.StopCleanup()
+
+	handler := New(cache, mockDB)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.OrderHandler(w, httptest.NewRequest("GET", "/order/unknown", nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", w.Code)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 database query for repeated misses, got %d", calls)
+	}
+
+	// Консюмер сохранил заказ — отметка об отсутствии снимается
+	cache.Add(models.Order{OrderUID: "unknown"})
+
+	w := httptest.NewRecorder()
+	handler.OrderHandler(w, httptest.NewRequest("GET", "/order/unknown", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after ingest, got %d", w.Code)
+	}
+}
+
+func TestHandler_CacheStats(t *testing.T) {
+	mockDB := &mockDB{
+		getByUIDFunc: func(uid string) (*models.Order, error) {
+			return &models.Order{OrderUID: uid}, nil
+		},
+	}
+
+	cache := cache.New(&config.CacheConfig{
+		MaxSize:         10,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+	})
+	defer cache.StopCleanup()
+
+	handler := New(cache, mockDB)
+
+	// Промах с загрузкой из БД, затем попадание
+	for i := 0; i < 2; i++ {
+		handler.OrderHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/order/test-123", nil))
+	}
+
+	w := httptest.NewRecorder()
+	handler.CacheStatsHandler(w, httptest.NewRequest("GET", "/cache/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var stats map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	for key, want := range map[string]float64{"count": 1, "hits": 1, "misses": 1, "inserts": 1, "hit_ratio": 0.5} {
+		if stats[key] != want {
+			t.Errorf("Expected %s = %v, got %v", key, want, stats[key])
+		}
+	}
+	if _, ok := stats["keys"]; ok {
+		t.Error("Expected keys not to be exposed")
+	}
+}
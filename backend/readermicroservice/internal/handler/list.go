@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// orderListResponse — ответ GET /orders. NextCursor пуст на последней странице
+type orderListResponse struct {
+	Orders     []models.Order `json:"orders"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// ListOrdersHandler отдает страницу заказов от новых к старым с фильтрами из query-параметров:
+// customer_id, delivery_service, locale, currency, bank, created_from, created_to (RFC 3339),
+// limit и cursor (next_cursor из предыдущего ответа)
+func (h *Handler) ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := parseOrderFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, after, err := parsePage(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.db.ListOrders(r.Context(), filter, after, limit)
+	if err != nil {
+		config.RLogger.Printf("Error listing orders: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, orderListResponse{
+		Orders:     page.Orders,
+		NextCursor: encodeCursor(page.Next),
+	})
+}
+
+func parseOrderFilter(q url.Values) (database.OrderFilter, error) {
+	filter := database.OrderFilter{
+		CustomerID:      q.Get("customer_id"),
+		DeliveryService: q.Get("delivery_service"),
+		Locale:          q.Get("locale"),
+		Currency:        q.Get("currency"),
+		Bank:            q.Get("bank"),
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(q, "created_from"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseTimeParam(q, "created_to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parsePage читает limit и cursor
+func parsePage(q url.Values) (int, *database.Cursor, error) {
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, nil, errors.New("limit must be a positive integer")
+		}
+		limit = min(n, maxPageSize)
+	}
+
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return limit, after, nil
+}
+
+func parseTimeParam(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	// date_created хранится как TIMESTAMP без зоны в UTC, а Postgres отбрасывает
+	// смещение параметра, поэтому границу нужно перевести в UTC
+	return t.UTC(), nil
+}
+
+// encodeCursor кодирует курсор в непрозрачную для клиента строку
+func encodeCursor(c *database.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*database.Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	errInvalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalid
+	}
+	created, uid, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errInvalid
+	}
+	t, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		return nil, errInvalid
+	}
+	return &database.Cursor{DateCreated: t, OrderUID: uid}, nil
+}
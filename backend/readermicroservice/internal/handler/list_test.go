@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+func TestHandler_ListOrders_FiltersAndCursor(t *testing.T) {
+	next := &database.Cursor{
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OrderUID:    "b563feb7b2b84b6test",
+	}
+
+	var gotFilter database.OrderFilter
+	var gotAfter *database.Cursor
+	var gotLimit int
+	db := &mockDB{
+		listOrdersFunc: func(filter database.OrderFilter, after *database.Cursor, limit int) (database.OrderPage, error) {
+			gotFilter, gotAfter, gotLimit = filter, after, limit
+			return database.OrderPage{Orders: []models.Order{{OrderUID: "b563feb7b2b84b6test"}}, Next: next}, nil
+		},
+	}
+	handler := New(nil, db)
+
+	w := httptest.NewRecorder()
+	handler.ListOrdersHandler(w, httptest.NewRequest("GET",
+		"/orders?customer_id=test&currency=USD&bank=alpha&created_from=2021-11-01T00:00:00Z&limit=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	wantFrom := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	if gotFilter.CustomerID != "test" || gotFilter.Currency != "USD" || gotFilter.Bank != "alpha" ||
+		!gotFilter.CreatedFrom.Equal(wantFrom) || !gotFilter.CreatedTo.IsZero() {
+		t.Errorf("Unexpected filter %+v", gotFilter)
+	}
+	if gotAfter != nil || gotLimit != 10 {
+		t.Errorf("Expected first page of 10, got cursor %v and limit %d", gotAfter, gotLimit)
+	}
+
+	var resp orderListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Orders) != 1 || resp.NextCursor == "" {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+
+	// Следующая страница запрашивается по курсору из ответа
+	w = httptest.NewRecorder()
+	handler.ListOrdersHandler(w, httptest.NewRequest("GET", "/orders?cursor="+resp.NextCursor, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if gotAfter == nil || *gotAfter != *next || gotLimit != defaultPageSize {
+		t.Errorf("Expected cursor %+v and default limit, got %+v and %d", next, gotAfter, gotLimit)
+	}
+}
+
+func TestHandler_ListOrders_TimeBoundsInUTC(t *testing.T) {
+	var gotFilter database.OrderFilter
+	db := &mockDB{
+		listOrdersFunc: func(filter database.OrderFilter, after *database.Cursor, limit int) (database.OrderPage, error) {
+			gotFilter = filter
+			return database.OrderPage{}, nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	New(nil, db).ListOrdersHandler(w, httptest.NewRequest("GET",
+		"/orders?created_from=2024-01-01T00:00:00%2B03:00", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	want := time.Date(2023, 12, 31, 21, 0, 0, 0, time.UTC)
+	if gotFilter.CreatedFrom != want {
+		t.Errorf("Expected bound normalized to %v, got %v", want, gotFilter.CreatedFrom)
+	}
+}
+
+func TestHandler_ListOrders_InvalidParams(t *testing.T) {
+	handler := New(nil, &mockDB{})
+
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=!!!", "created_to=yesterday"} {
+		w := httptest.NewRecorder()
+		handler.ListOrdersHandler(w, httptest.NewRequest("GET", "/orders?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+// OrderByTrackHandler ищет заказы по track_number: GET /orders/by-track/{track_number}
+func (h *Handler) OrderByTrackHandler(w http.ResponseWriter, r *http.Request) {
+	h.lookupOrders(w, r, database.LookupTrackNumber, r.PathValue("track_number"))
+}
+
+// OrderByTransactionHandler ищет заказы по payment.transaction: GET /orders/by-transaction/{transaction}
+func (h *Handler) OrderByTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	h.lookupOrders(w, r, database.LookupTransaction, r.PathValue("transaction"))
+}
+
+// OrderByRidHandler ищет заказы по rid товара: GET /orders/by-rid/{rid}
+func (h *Handler) OrderByRidHandler(w http.ResponseWriter, r *http.Request) {
+	h.lookupOrders(w, r, database.LookupItemRid, r.PathValue("rid"))
+}
+
+// lookupOrders отдает единственный найденный заказ так же, как OrderHandler, а если
+// идентификатору соответствует несколько заказов — их список, как GET /orders.
+// Статус в обоих случаях 200: 3xx без Location клиенты считают неудачным редиректом
+func (h *Handler) lookupOrders(w http.ResponseWriter, r *http.Request, by database.Lookup, value string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if value == "" {
+		http.Error(w, "Identifier is required", http.StatusBadRequest)
+		return
+	}
+
+	uids, err := h.db.FindOrderUIDs(r.Context(), by, value)
+	if err != nil {
+		config.RLogger.Printf("Error looking up orders by %q: %v", value, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	orders, _, err := h.ordersByUID(r.Context(), uids)
+	if err != nil {
+		config.RLogger.Printf("Error loading orders found by %q: %v", value, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch len(orders) {
+	case 0:
+		http.Error(w, "Order not found", http.StatusNotFound)
+	case 1:
+		respondWithJSON(w, http.StatusOK, orders[0])
+	default:
+		respondWithJSON(w, http.StatusOK, orderListResponse{Orders: orders})
+	}
+}
+
+// ordersByUID собирает заказы по списку order_uid: сначала одним обращением к кэшу,
+// а промахи — одним набором запросов к БД, после чего загруженные заказы кладутся
+// в кэш, а ненайденные отмечаются в негативном кэше, как в loadOrder.
+// Заказы возвращаются в порядке uids без повторов, missing — order_uid, которых нет в БД
+func (h *Handler) ordersByUID(ctx context.Context, uids []string) (orders []models.Order, missing []string, err error) {
+	unique := make([]string, 0, len(uids))
+	seen := make(map[string]bool, len(uids))
+	for _, uid := range uids {
+		if !seen[uid] {
+			seen[uid] = true
+			unique = append(unique, uid)
+		}
+	}
+
+	found, knownMissing := h.cache.GetMany(unique)
+	var misses []string
+	for _, uid := range unique {
+		if _, ok := found[uid]; !ok && !knownMissing[uid] {
+			misses = append(misses, uid)
+		}
+	}
+
+	if len(misses) > 0 {
+		loaded, err := h.db.GetByUIDs(ctx, misses)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, order := range loaded {
+			found[order.OrderUID] = order
+		}
+		h.cache.AddManyIfAbsent(loaded)
+
+		var notFound []string
+		for _, uid := range misses {
+			if _, ok := found[uid]; !ok {
+				notFound = append(notFound, uid)
+			}
+		}
+		h.cache.MarkManyMissing(notFound)
+	}
+
+	orders = make([]models.Order, 0, len(found))
+	for _, uid := range unique {
+		if order, ok := found[uid]; ok {
+			orders = append(orders, order)
+		} else {
+			missing = append(missing, uid)
+		}
+	}
+	return orders, missing, nil
+}
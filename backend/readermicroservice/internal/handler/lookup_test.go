@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/cache"
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+func newLookupMux(h *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orders/by-track/{track_number}", h.OrderByTrackHandler)
+	mux.HandleFunc("/orders/by-transaction/{transaction}", h.OrderByTransactionHandler)
+	mux.HandleFunc("/orders/by-rid/{rid}", h.OrderByRidHandler)
+	return mux
+}
+
+func TestHandler_Lookup_SingleOrderUsesCache(t *testing.T) {
+	var gotBy database.Lookup
+	var gotValue string
+	var loaded []string
+	db := &mockDB{
+		findUIDsFunc: func(by database.Lookup, value string) ([]string, error) {
+			gotBy, gotValue = by, value
+			return []string{"test-123"}, nil
+		},
+		getByUIDsFunc: func(uids []string) ([]models.Order, error) {
+			loaded = append(loaded, uids...)
+			return []models.Order{{OrderUID: "test-123", TrackNumber: "WB-TEST"}}, nil
+		},
+	}
+
+	c := cache.New(&config.CacheConfig{MaxSize: 10, DefaultTTL: time.Hour, CleanupInterval: time.Hour})
+	defer c.StopCleanup()
+	mux := newLookupMux(New(c, db))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/orders/by-track/WB-TEST", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var order models.Order
+		json.NewDecoder(w.Body).Decode(&order)
+		if order.OrderUID != "test-123" {
+			t.Errorf("Expected test-123, got %s", order.OrderUID)
+		}
+	}
+
+	if gotBy != database.LookupTrackNumber || gotValue != "WB-TEST" {
+		t.Errorf("Unexpected lookup %v %q", gotBy, gotValue)
+	}
+	if len(loaded) != 1 {
+		t.Errorf("Expected order to be loaded from DB once and then served from cache, got %v", loaded)
+	}
+}
+
+func TestHandler_Lookup_Ambiguous(t *testing.T) {
+	db := &mockDB{
+		findUIDsFunc: func(by database.Lookup, value string) ([]string, error) {
+			if by != database.LookupItemRid {
+				t.Errorf("Expected rid lookup, got %v", by)
+			}
+			return []string{"a", "b"}, nil
+		},
+		getByUIDsFunc: func(uids []string) ([]models.Order, error) {
+			return []models.Order{{OrderUID: "b"}}, nil
+		},
+	}
+
+	c := cache.New(&config.CacheConfig{MaxSize: 10, DefaultTTL: time.Hour, CleanupInterval: time.Hour})
+	defer c.StopCleanup()
+	c.Add(models.Order{OrderUID: "a"})
+
+	w := httptest.NewRecorder()
+	newLookupMux(New(c, db)).ServeHTTP(w, httptest.NewRequest("GET", "/orders/by-rid/rid-1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp orderListResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Orders) != 2 || resp.Orders[0].OrderUID != "a" || resp.Orders[1].OrderUID != "b" {
+		t.Errorf("Expected orders a, b, got %+v", resp.Orders)
+	}
+}
+
+func TestHandler_Lookup_NotFound(t *testing.T) {
+	c := cache.New(&config.CacheConfig{MaxSize: 10, DefaultTTL: time.Hour, CleanupInterval: time.Hour})
+	defer c.StopCleanup()
+
+	w := httptest.NewRecorder()
+	newLookupMux(New(c, &mockDB{})).ServeHTTP(w, httptest.NewRequest("GET", "/orders/by-transaction/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"unicode/utf8"
+
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/models"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+	// maxSearchOffset ограничивает глубину выдачи: OFFSET по релевантности
+	// дорожает с номером страницы, а дальше первой тысячи результатов не листают
+	maxSearchOffset   = 1000
+	maxSearchQueryLen = 256
+)
+
+// searchResult — найденный заказ с релевантностью и подсвеченными совпадениями
+type searchResult struct {
+	Order      models.Order        `json:"order"`
+	Rank       float64             `json:"rank"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// searchResponse — ответ GET /search. NextOffset пуст на последней странице
+type searchResponse struct {
+	Results    []searchResult `json:"results"`
+	NextOffset int            `json:"next_offset,omitempty"`
+}
+
+// SearchHandler ищет заказы по имени, email и городу получателя, бренду и названию
+// товара: GET /search?q=...&limit=...&offset=...
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := q.Get("q")
+	if query == "" {
+		http.Error(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		http.Error(w, "Search query is too long", http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parseSearchPage(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.db.Search(r.Context(), query, limit, offset)
+	if err != nil {
+		config.RLogger.Printf("Error searching orders for %q: %v", query, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	uids := make([]string, len(page.Hits))
+	for i, hit := range page.Hits {
+		uids[i] = hit.OrderUID
+	}
+	orders, _, err := h.ordersByUID(r.Context(), uids)
+	if err != nil {
+		config.RLogger.Printf("Error loading orders found for %q: %v", query, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	byUID := make(map[string]models.Order, len(orders))
+	for _, order := range orders {
+		byUID[order.OrderUID] = order
+	}
+
+	resp := searchResponse{Results: make([]searchResult, 0, len(page.Hits))}
+	for _, hit := range page.Hits {
+		// Заказ мог быть удален между поиском и загрузкой
+		order, ok := byUID[hit.OrderUID]
+		if !ok {
+			continue
+		}
+		resp.Results = append(resp.Results, searchResult{Order: order, Rank: hit.Rank, Highlights: hit.Highlights})
+	}
+	if page.HasMore && offset+limit <= maxSearchOffset {
+		resp.NextOffset = offset + limit
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// parseSearchPage читает limit и offset
+func parseSearchPage(q url.Values) (int, int, error) {
+	limit := defaultSearchPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		limit = min(n, maxSearchPageSize)
+	}
+
+	offset := 0
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxSearchOffset {
+			return 0, 0, errors.New("offset must be an integer between 0 and " + strconv.Itoa(maxSearchOffset))
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/cache"
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+)
+
+func TestHandler_Search(t *testing.T) {
+	var gotQuery string
+	var gotLimit, gotOffset int
+	db := &mockDB{
+		searchFunc: func(query string, limit, offset int) (database.SearchPage, error) {
+			gotQuery, gotLimit, gotOffset = query, limit, offset
+			return database.SearchPage{
+				Hits: []database.SearchHit{
+					{OrderUID: "b", Rank: 0.9, Highlights: map[string][]string{"items.brand": {"<mark>Vivienne</mark> Sabo"}}},
+					{OrderUID: "gone", Rank: 0.5},
+					{OrderUID: "a", Rank: 0.1},
+				},
+				HasMore: true,
+			}, nil
+		},
+		getByUIDsFunc: func(uids []string) ([]models.Order, error) {
+			return []models.Order{{OrderUID: "b"}}, nil
+		},
+	}
+
+	c := cache.New(&config.CacheConfig{MaxSize: 10, DefaultTTL: time.Hour, CleanupInterval: time.Hour})
+	defer c.StopCleanup()
+	c.Add(models.Order{OrderUID: "a"})
+
+	w := httptest.NewRecorder()
+	New(c, db).SearchHandler(w, httptest.NewRequest("GET", "/search?q=vivienne+sabo&limit=3&offset=6", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if gotQuery != "vivienne sabo" || gotLimit != 3 || gotOffset != 6 {
+		t.Errorf("Unexpected search arguments %q %d %d", gotQuery, gotLimit, gotOffset)
+	}
+
+	var resp searchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Order.OrderUID != "b" || resp.Results[1].Order.OrderUID != "a" {
+		t.Fatalf("Expected ranked results without deleted order, got %+v", resp.Results)
+	}
+	if got := resp.Results[0].Highlights["items.brand"]; len(got) != 1 || got[0] != "<mark>Vivienne</mark> Sabo" {
+		t.Errorf("Expected brand highlight, got %v", resp.Results[0].Highlights)
+	}
+	if resp.NextOffset != 9 {
+		t.Errorf("Expected next offset 9, got %d", resp.NextOffset)
+	}
+}
+
+func TestHandler_Search_BadRequest(t *testing.T) {
+	h := New(nil, &mockDB{})
+	for _, target := range []string{"/search", "/search?q=x&limit=0", "/search?q=x&offset=-1", "/search?q=x&offset=5000"} {
+		w := httptest.NewRecorder()
+		h.SearchHandler(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, w.Code)
+		}
+	}
+}
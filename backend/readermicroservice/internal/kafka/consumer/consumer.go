@@ -3,7 +3,9 @@ package consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"readermicroservice/internal/cache"
@@ -14,8 +16,24 @@ import (
 	"github.com/segmentio/kafka-go"
 )
 
+const maxRedeliveryDelay = 30 * time.Second
+
+// messageReader — часть kafka.Reader, которой пользуется Consumer
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// messageWriter — часть kafka.Writer, через которую сообщения отправляются в DLQ
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 type Consumer struct {
-	reader *kafka.Reader
+	reader messageReader
+	dlq    messageWriter // nil, если DLQ не настроена
 	cache  cache.OrderCache
 	db     database.Database
 	config *config.AppConfig
@@ -31,6 +49,7 @@ func New(cfg *config.AppConfig, c cache.OrderCache, db database.Database) (*Cons
 
 	return &Consumer{
 		reader: reader,
+		dlq:    newDLQWriter(cfg.Kafka.Brokers, cfg.Kafka.DLQTopic),
 		cache:  c,
 		db:     db,
 		config: cfg,
@@ -55,50 +74,147 @@ func (c *Consumer) Listen(ctx context.Context) error {
 	}
 }
 
+// processMessage читает одно сообщение и коммитит его offset только после того,
+// как заказ сохранен в БД или сообщение отправлено в DLQ
 func (c *Consumer) processMessage(ctx context.Context) error {
-	msg, err := c.reader.ReadMessage(ctx)
+	msg, err := c.reader.FetchMessage(ctx)
 	if err != nil {
-		return fmt.Errorf("error reading message: %w", err)
+		return fmt.Errorf("error fetching message: %w", err)
 	}
 
 	config.RLogger.Printf("Received message from partition %d, offset %d",
 		msg.Partition, msg.Offset)
 
+	// Пока сообщение не обработано, offset не двигаем: повторяем обработку того же сообщения
+	for attempt := 1; ; attempt++ {
+		err = c.handleMessage(ctx, msg)
+		if err == nil {
+			break
+		}
+
+		config.RLogger.Printf("Message from partition %d, offset %d not handled (attempt %d): %v",
+			msg.Partition, msg.Offset, attempt, err)
+
+		if err := sleepCtx(ctx, redeliveryDelay(c.config.Retry.BaseDelay, attempt)); err != nil {
+			return fmt.Errorf("message from partition %d, offset %d left uncommitted: %w",
+				msg.Partition, msg.Offset, err)
+		}
+	}
+
+	if err := c.reader.CommitMessages(ctx, msg); err != nil {
+		return fmt.Errorf("error committing offset %d: %w", msg.Offset, err)
+	}
+
+	return nil
+}
+
+// handleMessage возвращает nil, если сообщение можно коммитить:
+// заказ сохранен, сообщение ушло в DLQ или заведомо не может быть обработано
+func (c *Consumer) handleMessage(ctx context.Context, msg kafka.Message) error {
 	var order models.Order
-	if err = json.Unmarshal(msg.Value, &order); err != nil {
-		return fmt.Errorf("error unmarshaling message: %w", err)
+	if err := json.Unmarshal(msg.Value, &order); err != nil {
+		err = fmt.Errorf("error unmarshaling message: %w", err)
+		return c.reject(ctx, msg, stageDecode, err, 1)
 	}
 
-	if order.OrderUID == "" {
-		return fmt.Errorf("received order with empty OrderUID")
+	// Невалидный заказ не должен попасть ни в кэш, ни в БД
+	if err := order.Validate(); err != nil {
+		return c.reject(ctx, msg, stageValidate, err, 1)
 	}
 
-	c.cache.Add(order)
+	if err := c.reconcile(&order); err != nil {
+		return c.reject(ctx, msg, stageReconcile, err, 1)
+	}
 
-	if err := c.insertWithRetry(order); err != nil {
+	err := c.insertWithRetry(ctx, order)
+	if errors.Is(err, database.ErrStaleVersion) {
+		// Запоздалая доставка старой версии: в БД и кэше остается более новая
+		config.RLogger.Printf("Skipping stale version of order %s", order.OrderUID)
+		return nil
+	}
+	if err != nil {
+		// При остановке сервиса сообщение не отправляется в DLQ, а остается незакоммиченным
+		if ctx.Err() != nil {
+			return err
+		}
 		config.RLogger.Printf("Failed to insert order %s after retries: %v",
 			order.OrderUID, err)
-		// Здесь можно отправить в DLQ
-		return err
+		return c.reject(ctx, msg, stagePersist, err, c.config.Retry.MaxRetries)
 	}
 
+	// Кэш обновляется только после того, как заказ закоммичен в БД
+	c.cache.Add(order)
+
 	config.RLogger.Printf("Successfully processed order %s", order.OrderUID)
 	return nil
 }
 
-func (c *Consumer) insertWithRetry(order models.Order) error {
+// reconcile сверяет суммы заказа согласно настроенному режиму. В строгом режиме
+// расхождения возвращаются как ошибка, в нестрогом — сохраняются в заказе
+func (c *Consumer) reconcile(order *models.Order) error {
+	// Расхождения всегда вычисляются заново, а не берутся из входящего сообщения
+	order.Discrepancies = nil
+	if c.config.Reconcile.Mode == config.ReconcileOff {
+		return nil
+	}
+
+	discrepancies := order.Reconcile()
+	if len(discrepancies) == 0 {
+		return nil
+	}
+
+	if c.config.Reconcile.Mode == config.ReconcileStrict {
+		parts := make([]string, 0, len(discrepancies))
+		for _, d := range discrepancies {
+			parts = append(parts, d.String())
+		}
+		return fmt.Errorf("order %s failed reconciliation: %s", order.OrderUID, strings.Join(parts, "; "))
+	}
+
+	config.RLogger.Printf("Order %s has %d reconciliation discrepancies, storing flagged",
+		order.OrderUID, len(discrepancies))
+	order.Discrepancies = discrepancies
+	return nil
+}
+
+// reject отправляет сообщение в DLQ. Ошибка возвращается только тогда, когда сообщение
+// нельзя коммитить: DLQ недоступна или не настроена, а заказ еще может быть сохранен повторно
+func (c *Consumer) reject(ctx context.Context, msg kafka.Message, stage string, cause error, attempts int) error {
+	if c.dlq == nil {
+		if stage == stagePersist {
+			return cause
+		}
+		config.RLogger.Printf("Dropping message from partition %d, offset %d at stage %s: %v",
+			msg.Partition, msg.Offset, stage, cause)
+		return nil
+	}
+
+	if err := c.sendToDLQ(ctx, msg, stage, cause, attempts); err != nil {
+		return fmt.Errorf("%w (dlq: %v)", cause, err)
+	}
+
+	config.RLogger.Printf("Message from partition %d, offset %d sent to DLQ at stage %s: %v",
+		msg.Partition, msg.Offset, stage, cause)
+	return nil
+}
+
+func (c *Consumer) insertWithRetry(ctx context.Context, order models.Order) error {
 	var lastErr error
 
 	for i := 0; i < c.config.Retry.MaxRetries; i++ {
 		if err := c.db.Insert(order); err == nil {
 			return nil
+		} else if errors.Is(err, database.ErrStaleVersion) {
+			return err
 		} else {
 			lastErr = err
 			config.RLogger.Printf("Error inserting order %s (attempt %d/%d): %v",
 				order.OrderUID, i+1, c.config.Retry.MaxRetries, err)
 
 			if i < c.config.Retry.MaxRetries-1 {
-				time.Sleep(time.Duration(i+1) * c.config.Retry.BaseDelay)
+				if err := sleepCtx(ctx, time.Duration(i+1)*c.config.Retry.BaseDelay); err != nil {
+					return fmt.Errorf("insert of order %s interrupted: %w", order.OrderUID, err)
+				}
 			}
 		}
 	}
@@ -106,6 +222,33 @@ func (c *Consumer) insertWithRetry(order models.Order) error {
 	return fmt.Errorf("failed after %d attempts: %w", c.config.Retry.MaxRetries, lastErr)
 }
 
+// redeliveryDelay возвращает паузу перед повторной обработкой сообщения, не больше maxRedeliveryDelay
+func redeliveryDelay(base time.Duration, attempt int) time.Duration {
+	delay := time.Duration(attempt) * base
+	if delay <= 0 || delay > maxRedeliveryDelay {
+		return maxRedeliveryDelay
+	}
+	return delay
+}
+
+// sleepCtx ждет d или отмены контекста
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (c *Consumer) Close() error {
+	if c.dlq != nil {
+		if err := c.dlq.Close(); err != nil {
+			config.RLogger.Printf("Error closing DLQ writer: %v", err)
+		}
+	}
 	return c.reader.Close()
 }
@@ -0,0 +1,368 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"readermicroservice/internal/cache"
+	"readermicroservice/internal/config"
+	"readermicroservice/internal/database"
+	"readermicroservice/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestMain(m *testing.M) {
+	config.RLogger = log.New(os.Stdout, "TEST: ", log.LstdFlags)
+	os.Exit(m.Run())
+}
+
+// fakeReader отдает одно сообщение и запоминает закоммиченные offset
+type fakeReader struct {
+	msg       kafka.Message
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	return r.msg, nil
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// fakeWriter — DLQ, которая отвечает ошибкой, пока failures не исчерпаны
+// (отрицательное значение — всегда); onFail вызывается после каждой неудачной записи
+type fakeWriter struct {
+	mu       sync.Mutex
+	failures int
+	onFail   func()
+	written  []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures != 0 {
+		w.failures--
+		if w.onFail != nil {
+			w.onFail()
+		}
+		return errors.New("broker unavailable")
+	}
+	w.written = append(w.written, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+// stubDB реализует только Insert; остальные методы Consumer не вызывает
+type stubDB struct {
+	database.Database
+	insert  func(models.Order) error
+	inserts int
+}
+
+func (db *stubDB) Insert(order models.Order) error {
+	db.inserts++
+	return db.insert(order)
+}
+
+func validOrder() models.Order {
+	return models.Order{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{{
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			TotalPrice:  317,
+		}},
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	return b
+}
+
+func headerValue(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestConsumer_ProcessMessage(t *testing.T) {
+	invalid := validOrder()
+	invalid.OrderUID = ""
+
+	insertErr := errors.New("connection refused")
+
+	tests := []struct {
+		name         string
+		value        []byte
+		insertErr    error
+		dlq          *fakeWriter // nil — DLQ не настроена
+		cancelOnFail bool        // остановить сервис после неудачной записи в DLQ
+
+		wantErr       bool
+		wantCommitted bool
+		wantInserts   int
+		wantCached    bool
+		wantStage     string // пусто — в DLQ ничего не отправлено
+		wantAttempts  int
+	}{
+		{
+			name:          "success",
+			value:         mustMarshal(t, validOrder()),
+			dlq:           &fakeWriter{},
+			wantCommitted: true,
+			wantInserts:   1,
+			wantCached:    true,
+		},
+		{
+			name:          "decode failure",
+			value:         []byte("{not json"),
+			dlq:           &fakeWriter{},
+			wantCommitted: true,
+			wantStage:     stageDecode,
+			wantAttempts:  1,
+		},
+		{
+			name:          "validation failure",
+			value:         mustMarshal(t, invalid),
+			dlq:           &fakeWriter{},
+			wantCommitted: true,
+			wantStage:     stageValidate,
+			wantAttempts:  1,
+		},
+		{
+			name:          "persist failure",
+			value:         mustMarshal(t, validOrder()),
+			insertErr:     insertErr,
+			dlq:           &fakeWriter{},
+			wantCommitted: true,
+			wantInserts:   3,
+			wantStage:     stagePersist,
+			wantAttempts:  3,
+		},
+		{
+			name:          "stale redelivery",
+			value:         mustMarshal(t, validOrder()),
+			insertErr:     database.ErrStaleVersion,
+			dlq:           &fakeWriter{},
+			wantCommitted: true,
+			wantInserts:   1,
+		},
+		{
+			name:          "dlq recovers after failed write",
+			value:         []byte("{not json"),
+			dlq:           &fakeWriter{failures: 2},
+			wantCommitted: true,
+			wantStage:     stageDecode,
+			wantAttempts:  1,
+		},
+		{
+			name:         "dlq unavailable",
+			value:        []byte("{not json"),
+			dlq:          &fakeWriter{failures: -1},
+			cancelOnFail: true,
+			wantErr:      true,
+		},
+		{
+			name:          "validation failure waits for dlq",
+			value:         mustMarshal(t, invalid),
+			dlq:           &fakeWriter{failures: 2},
+			wantCommitted: true,
+			wantStage:     stageValidate,
+			wantAttempts:  1,
+		},
+		{
+			name:         "validation failure with dlq unavailable",
+			value:        mustMarshal(t, invalid),
+			dlq:          &fakeWriter{failures: -1},
+			cancelOnFail: true,
+			wantErr:      true,
+		},
+		{
+			name:         "persist failure with dlq unavailable",
+			value:        mustMarshal(t, validOrder()),
+			insertErr:    insertErr,
+			dlq:          &fakeWriter{failures: -1},
+			cancelOnFail: true,
+			wantErr:      true,
+			wantInserts:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			reader := &fakeReader{msg: kafka.Message{Topic: "orders", Partition: 2, Offset: 42, Key: []byte("key"), Value: tt.value}}
+			db := &stubDB{insert: func(models.Order) error { return tt.insertErr }}
+			c := cache.New(&config.CacheConfig{MaxSize: 10, DefaultTTL: time.Hour, CleanupInterval: time.Hour})
+			defer c.StopCleanup()
+
+			consumer := &Consumer{
+				reader: reader,
+				cache:  c,
+				db:     db,
+				config: &config.AppConfig{
+					Retry:     config.RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond},
+					Reconcile: config.ReconcileConfig{Mode: config.ReconcileOff},
+				},
+			}
+			if tt.dlq != nil {
+				if tt.cancelOnFail {
+					tt.dlq.onFail = cancel
+				}
+				consumer.dlq = tt.dlq
+			}
+
+			err := consumer.processMessage(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if committed := len(reader.committed) == 1 && reader.committed[0] == 42; committed != tt.wantCommitted {
+				t.Errorf("Expected committed %v, got offsets %v", tt.wantCommitted, reader.committed)
+			}
+			if db.inserts != tt.wantInserts {
+				t.Errorf("Expected %d inserts, got %d", tt.wantInserts, db.inserts)
+			}
+			if _, cached := c.Get(validOrder().OrderUID); cached != tt.wantCached {
+				t.Errorf("Expected cached %v", tt.wantCached)
+			}
+
+			var written []kafka.Message
+			if tt.dlq != nil {
+				written = tt.dlq.written
+			}
+			if tt.wantStage == "" {
+				if len(written) != 0 {
+					t.Errorf("Expected nothing in DLQ, got %d messages", len(written))
+				}
+				return
+			}
+			if len(written) != 1 {
+				t.Fatalf("Expected one DLQ message, got %d", len(written))
+			}
+
+			msg := written[0]
+			if string(msg.Key) != "key" || string(msg.Value) != string(tt.value) {
+				t.Errorf("Expected original key and value in DLQ, got %q %q", msg.Key, msg.Value)
+			}
+			want := map[string]string{
+				headerStage:           tt.wantStage,
+				headerSourceTopic:     "orders",
+				headerSourcePartition: "2",
+				headerSourceOffset:    "42",
+				headerAttempts:        strconv.Itoa(tt.wantAttempts),
+			}
+			for key, value := range want {
+				if got := headerValue(msg, key); got != value {
+					t.Errorf("Expected header %s = %q, got %q", key, value, got)
+				}
+			}
+			if headerValue(msg, headerError) == "" || headerValue(msg, headerFailedAt) == "" {
+				t.Errorf("Expected error and failure time headers, got %v", msg.Headers)
+			}
+		})
+	}
+}
+
+func TestConsumer_ProcessMessage_WithoutDLQ(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         []byte
+		wantCommitted bool
+	}{
+		// Необрабатываемое сообщение без DLQ отбрасывается, чтобы не блокировать партицию
+		{name: "decode failure is dropped", value: []byte("{not json"), wantCommitted: true},
+		// Заказ, который не удалось сохранить, остается незакоммиченным до остановки
+		{name: "persist failure is kept", value: mustMarshal(t, validOrder())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			reader := &fakeReader{msg: kafka.Message{Offset: 7, Value: tt.value}}
+			db := &stubDB{insert: func(models.Order) error {
+				cancel()
+				return errors.New("connection refused")
+			}}
+			consumer := &Consumer{
+				reader: reader,
+				db:     db,
+				config: &config.AppConfig{Retry: config.RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond}},
+			}
+
+			err := consumer.processMessage(ctx)
+			committed := len(reader.committed) == 1
+			if committed != tt.wantCommitted || (err != nil) == tt.wantCommitted {
+				t.Errorf("Expected committed %v, got offsets %v and error %v", tt.wantCommitted, reader.committed, err)
+			}
+		})
+	}
+}
+
+func TestNewDLQWriter(t *testing.T) {
+	if w := newDLQWriter([]string{"kafka:9092"}, ""); w != nil {
+		t.Errorf("Expected no writer without a DLQ topic, got %T", w)
+	}
+
+	w, ok := newDLQWriter([]string{"kafka:9092"}, "orders-dlq").(*kafka.Writer)
+	if !ok {
+		t.Fatal("Expected a kafka writer")
+	}
+	defer w.Close()
+	// Топик orders-dlq нигде не создается заранее
+	if !w.AllowAutoTopicCreation {
+		t.Error("Expected DLQ writer to create its topic on first write")
+	}
+}
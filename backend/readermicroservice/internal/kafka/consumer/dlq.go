@@ -0,0 +1,76 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Этапы обработки, на которых сообщение может попасть в DLQ
+const (
+	stageDecode    = "decode"
+	stageValidate  = "validate"
+	stageReconcile = "reconcile"
+	stagePersist   = "persist"
+)
+
+// Заголовки, которые добавляются к сообщению при отправке в DLQ
+const (
+	headerStage           = "x-dlq-stage"
+	headerError           = "x-dlq-error"
+	headerSourceTopic     = "x-dlq-source-topic"
+	headerSourcePartition = "x-dlq-source-partition"
+	headerSourceOffset    = "x-dlq-source-offset"
+	headerAttempts        = "x-dlq-attempts"
+	headerFailedAt        = "x-dlq-failed-at"
+)
+
+// newDLQWriter создает writer для dead-letter топика или возвращает nil, если топик не задан.
+// Топик создается при первой записи: без него каждая запись в DLQ завершалась бы
+// ошибкой, и первое же необрабатываемое сообщение навсегда останавливало бы партицию
+func newDLQWriter(brokers []string, topic string) messageWriter {
+	if topic == "" {
+		return nil
+	}
+
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		BatchSize:              1,
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+}
+
+// sendToDLQ републикует исходное сообщение в dead-letter топик с описанием ошибки в заголовках
+func (c *Consumer) sendToDLQ(ctx context.Context, msg kafka.Message, stage string, cause error, attempts int) error {
+	if c.dlq == nil {
+		return fmt.Errorf("dlq topic is not configured")
+	}
+
+	headers := make([]kafka.Header, 0, len(msg.Headers)+7)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: headerStage, Value: []byte(stage)},
+		kafka.Header{Key: headerError, Value: []byte(cause.Error())},
+		kafka.Header{Key: headerSourceTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: headerSourcePartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: headerSourceOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: headerAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: headerFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	err := c.dlq.WriteMessages(ctx, kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing message to dlq: %w", err)
+	}
+
+	return nil
+}
@@ -25,6 +25,9 @@ type Order struct {
 	SmID              int       `json:"sm_id"`
 	DateCreated       time.Time `json:"date_created"`
 	OofShard          string    `json:"oof_shard"`
+
+	// Discrepancies заполняется при сверке сумм в нестрогом режиме
+	Discrepancies []Discrepancy `json:"discrepancies,omitempty"`
 }
 
 type Delivery struct {
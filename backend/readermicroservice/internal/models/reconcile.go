@@ -0,0 +1,53 @@
+package models
+
+import "fmt"
+
+// Discrepancy описывает расхождение между суммами в оплате и в товарах заказа
+type Discrepancy struct {
+	Field    string `json:"field"`
+	Expected int    `json:"expected"`
+	Actual   int    `json:"actual"`
+}
+
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("%s: expected %d, got %d", d.Field, d.Expected, d.Actual)
+}
+
+// Reconcile сверяет суммы заказа:
+//   - total_price каждого товара равен price со скидкой sale
+//   - payment.goods_total равен сумме total_price товаров
+//   - payment.amount равен goods_total + delivery_cost + custom_fee
+func (o Order) Reconcile() []Discrepancy {
+	var result []Discrepancy
+
+	goodsTotal := 0
+	for i, it := range o.Items {
+		if expected := it.Price * (100 - it.Sale) / 100; it.TotalPrice != expected {
+			result = append(result, Discrepancy{
+				Field:    fmt.Sprintf("items[%d].total_price", i),
+				Expected: expected,
+				Actual:   it.TotalPrice,
+			})
+		}
+		goodsTotal += it.TotalPrice
+	}
+
+	if o.Payment.GoodsTotal != goodsTotal {
+		result = append(result, Discrepancy{
+			Field:    "payment.goods_total",
+			Expected: goodsTotal,
+			Actual:   o.Payment.GoodsTotal,
+		})
+	}
+
+	expectedAmount := o.Payment.GoodsTotal + o.Payment.DeliveryCost + o.Payment.CustomFee
+	if o.Payment.Amount != expectedAmount {
+		result = append(result, Discrepancy{
+			Field:    "payment.amount",
+			Expected: expectedAmount,
+			Actual:   o.Payment.Amount,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	emailRe = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	phoneRe = regexp.MustCompile(`^\+?[0-9][0-9\s()-]{5,19}$`)
+)
+
+// FieldError описывает ошибку в одном поле заказа
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError содержит все найденные в заказе ошибки
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return "invalid order: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *ValidationError) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		e.add(field, "is required")
+	}
+}
+
+func (e *ValidationError) nonNegative(field string, value int) {
+	if value < 0 {
+		e.add(field, "must be non-negative, got %d", value)
+	}
+}
+
+// Validate проверяет заказ перед сохранением. Возвращает *ValidationError со списком
+// ошибок по полям или nil, если заказ корректен
+func (o Order) Validate() error {
+	verr := &ValidationError{}
+
+	verr.required("order_uid", o.OrderUID)
+	verr.required("track_number", o.TrackNumber)
+	verr.required("entry", o.Entry)
+	verr.required("locale", o.Locale)
+	verr.required("customer_id", o.CustomerID)
+	verr.required("delivery_service", o.DeliveryService)
+	if o.DateCreated.IsZero() {
+		verr.add("date_created", "is required")
+	}
+
+	verr.required("delivery.name", o.Delivery.Name)
+	verr.required("delivery.city", o.Delivery.City)
+	verr.required("delivery.address", o.Delivery.Address)
+	if o.Delivery.Phone == "" {
+		verr.add("delivery.phone", "is required")
+	} else if !phoneRe.MatchString(o.Delivery.Phone) {
+		verr.add("delivery.phone", "invalid phone format %q", o.Delivery.Phone)
+	}
+	if o.Delivery.Email == "" {
+		verr.add("delivery.email", "is required")
+	} else if !emailRe.MatchString(o.Delivery.Email) {
+		verr.add("delivery.email", "invalid email format %q", o.Delivery.Email)
+	}
+
+	verr.required("payment.transaction", o.Payment.Transaction)
+	if o.Payment.Transaction != "" && o.Payment.Transaction != o.OrderUID {
+		verr.add("payment.transaction", "must match order_uid %q, got %q", o.OrderUID, o.Payment.Transaction)
+	}
+	verr.required("payment.currency", o.Payment.Currency)
+	verr.required("payment.provider", o.Payment.Provider)
+	verr.nonNegative("payment.amount", o.Payment.Amount)
+	verr.nonNegative("payment.delivery_cost", o.Payment.DeliveryCost)
+	verr.nonNegative("payment.goods_total", o.Payment.GoodsTotal)
+	verr.nonNegative("payment.custom_fee", o.Payment.CustomFee)
+	if o.Payment.PaymentDt < 0 {
+		verr.add("payment.payment_dt", "must be non-negative, got %d", o.Payment.PaymentDt)
+	}
+
+	if len(o.Items) == 0 {
+		verr.add("items", "must contain at least one item")
+	}
+	for i, it := range o.Items {
+		prefix := fmt.Sprintf("items[%d].", i)
+		verr.required(prefix+"rid", it.Rid)
+		verr.required(prefix+"name", it.Name)
+		if it.TrackNumber != o.TrackNumber {
+			verr.add(prefix+"track_number", "must match order track_number %q, got %q", o.TrackNumber, it.TrackNumber)
+		}
+		verr.nonNegative(prefix+"price", it.Price)
+		verr.nonNegative(prefix+"total_price", it.TotalPrice)
+		if it.Sale < 0 || it.Sale > 100 {
+			verr.add(prefix+"sale", "must be between 0 and 100, got %d", it.Sale)
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func validOrder() Order {
+	return Order{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery: Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Email:   "test@gmail.com",
+		},
+		Payment: Payment{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []Item{{
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			TotalPrice:  317,
+		}},
+	}
+}
+
+func TestOrder_Validate_Valid(t *testing.T) {
+	if err := validOrder().Validate(); err != nil {
+		t.Errorf("Expected valid order, got %v", err)
+	}
+}
+
+func TestOrder_Validate_FieldErrors(t *testing.T) {
+	order := validOrder()
+	order.Delivery.Email = "not-an-email"
+	order.Payment.Transaction = "other"
+	order.Payment.Amount = -1
+	order.Items[0].TrackNumber = "OTHER"
+
+	err := order.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+
+	want := map[string]bool{
+		"delivery.email":        true,
+		"payment.transaction":   true,
+		"payment.amount":        true,
+		"items[0].track_number": true,
+	}
+	for _, fe := range verr.Errors {
+		if !want[fe.Field] {
+			t.Errorf("Unexpected field error %s: %s", fe.Field, fe.Message)
+		}
+		delete(want, fe.Field)
+	}
+	for field := range want {
+		t.Errorf("Expected error for field %s", field)
+	}
+}
+
+func TestOrder_Validate_NoItems(t *testing.T) {
+	order := validOrder()
+	order.Items = nil
+
+	var verr *ValidationError
+	if !errors.As(order.Validate(), &verr) || len(verr.Errors) != 1 || verr.Errors[0].Field != "items" {
+		t.Errorf("Expected single items error, got %v", verr)
+	}
+}
+
+func TestOrder_Reconcile(t *testing.T) {
+	order := validOrder()
+	if d := order.Reconcile(); len(d) != 0 {
+		t.Fatalf("Expected no discrepancies, got %v", d)
+	}
+
+	order.Items[0].TotalPrice = 300
+	d := order.Reconcile()
+	if len(d) != 2 {
+		t.Fatalf("Expected 2 discrepancies, got %v", d)
+	}
+	if d[0].Field != "items[0].total_price" || d[0].Expected != 317 || d[0].Actual != 300 {
+		t.Errorf("Unexpected item discrepancy: %v", d[0])
+	}
+	if d[1].Field != "payment.goods_total" || d[1].Expected != 300 || d[1].Actual != 317 {
+		t.Errorf("Unexpected goods_total discrepancy: %v", d[1])
+	}
+}
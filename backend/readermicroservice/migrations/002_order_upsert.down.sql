@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_items_order_uid;
+DROP INDEX IF EXISTS idx_payments_order_uid;
+DROP INDEX IF EXISTS idx_delivery_order_uid;
+
+ALTER TABLE IF EXISTS orders DROP COLUMN IF EXISTS payload_hash;
\ No newline at end of file
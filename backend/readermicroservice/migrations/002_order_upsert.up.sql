@@ -0,0 +1,5 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payload_hash CHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_order_uid ON delivery(order_uid);
+CREATE INDEX IF NOT EXISTS idx_payments_order_uid ON payments(order_uid);
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items(order_uid);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discrepancies JSONB;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_orders_date_created;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders(date_created DESC, order_uid DESC);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payments_bank;
+DROP INDEX IF EXISTS idx_payments_currency;
+DROP INDEX IF EXISTS idx_orders_locale_created;
+DROP INDEX IF EXISTS idx_orders_delivery_service_created;
+DROP INDEX IF EXISTS idx_orders_customer_created;
\ No newline at end of file
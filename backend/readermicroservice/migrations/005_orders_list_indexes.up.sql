@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_orders_customer_created ON orders(customer_id, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service_created ON orders(delivery_service, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_locale_created ON orders(locale, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_payments_currency ON payments(currency);
+CREATE INDEX IF NOT EXISTS idx_payments_bank ON payments(bank);
\ No newline at end of file
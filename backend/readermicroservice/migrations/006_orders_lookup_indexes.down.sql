@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_items_rid;
+DROP INDEX IF EXISTS idx_payments_transaction;
+DROP INDEX IF EXISTS idx_orders_track_number;
\ No newline at end of file
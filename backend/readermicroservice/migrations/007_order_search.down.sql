@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_search;
+ALTER TABLE orders DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS order_search_vector(VARCHAR);
+DROP AGGREGATE IF EXISTS tsvector_agg(tsvector);
+ALTER TABLE items DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE delivery DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
@@ -0,0 +1,29 @@
+ALTER TABLE delivery ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(email, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(city, '')), 'B')
+  ) STORED;
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(brand, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(name, '')), 'B')
+  ) STORED;
+
+CREATE OR REPLACE AGGREGATE tsvector_agg(tsvector) (
+  SFUNC = tsvector_concat,
+  STYPE = tsvector,
+  INITCOND = ''
+);
+
+CREATE OR REPLACE FUNCTION order_search_vector(uid VARCHAR) RETURNS tsvector
+  LANGUAGE SQL STABLE AS $$
+    SELECT coalesce((SELECT tsvector_agg(search_vector) FROM delivery WHERE order_uid = uid), '') ||
+           coalesce((SELECT tsvector_agg(search_vector) FROM items WHERE order_uid = uid), '')
+  $$;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE orders SET search_vector = order_search_vector(order_uid);
+
+CREATE INDEX IF NOT EXISTS idx_orders_search ON orders USING GIN (search_vector);
\ No newline at end of file